@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
 
+	"github.com/go-ldap/ldap/v3"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
 	"github.com/trevex/terraform-provider-ldap/provider"
 )
@@ -19,6 +25,14 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		if err := generate(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var debugMode bool
 
 	flag.BoolVar(&debugMode, "debug", false, "set to true to run the provider with support for debuggers like delve")
@@ -36,3 +50,71 @@ func main() {
 
 	plugin.Serve(opts)
 }
+
+// generate writes the configuration for the entries of an existing subtree,
+// e.g. terraform-provider-ldap generate --url ldap://localhost --base-dn dc=example,dc=com
+func generate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	url := fs.String("url", os.Getenv("LDAP_URL"), "the URL of the LDAP server")
+	useStartTLS := fs.Bool("use-starttls", os.Getenv("LDAP_USE_STARTTLS") == "true", "whether to use StartTLS")
+	skipVerify := fs.Bool("skip-verify", os.Getenv("LDAP_SKIP_VERIFY") == "true", "whether to skip the verification of the server certificate")
+	bindUser := fs.String("bind-user", os.Getenv("LDAP_BIND_USER"), "the DN to bind as")
+	bindPassword := fs.String("bind-password", os.Getenv("LDAP_BIND_PASSWORD"), "the password to bind with")
+	baseDN := fs.String("base-dn", "", "the DN of the root of the subtree")
+	scope := fs.String("scope", "sub", "the scope of the search: base, one or sub")
+	filter := fs.String("filter", "(objectClass=*)", "the filter selecting the entries")
+	skip := fs.String("skip", "", "a comma-separated list of further attributes to leave out")
+	output := fs.String("output", "", "the file to write the configuration to, instead of the standard output")
+	fs.Parse(args)
+
+	if *url == "" || *baseDN == "" {
+		return fmt.Errorf("--url and --base-dn are required")
+	}
+	scopes := map[string]int{
+		"base": ldap.ScopeBaseObject,
+		"one":  ldap.ScopeSingleLevel,
+		"sub":  ldap.ScopeWholeSubtree,
+	}
+	s, ok := scopes[*scope]
+	if !ok {
+		return fmt.Errorf("invalid scope %q, expected base, one or sub", *scope)
+	}
+	config := provider.GenerateConfig{
+		BaseDN: *baseDN,
+		Scope:  s,
+		Filter: *filter,
+	}
+	for _, name := range strings.Split(*skip, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			config.SkipAttributes = append(config.SkipAttributes, name)
+		}
+	}
+
+	tlsConfig := tls.Config{InsecureSkipVerify: *skipVerify}
+	conn, err := ldap.DialURL(*url, ldap.DialWithTLSConfig(&tlsConfig))
+	if err != nil {
+		return fmt.Errorf("connecting to ldap server failed with: %v", err)
+	}
+	defer conn.Close()
+	if *useStartTLS {
+		if err := conn.StartTLS(&tlsConfig); err != nil {
+			return fmt.Errorf("establishing StartTLS session failed with: %v", err)
+		}
+	}
+	if *bindUser != "" {
+		if err := conn.Bind(*bindUser, *bindPassword); err != nil {
+			return fmt.Errorf("binding user failed with: %v", err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return provider.Generate(context.Background(), conn, config, w)
+}
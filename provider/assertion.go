@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"github.com/trevex/terraform-provider-ldap/util"
+)
+
+// the OID of the assertion control (RFC 4528)
+const assertionControlOID = "1.3.6.1.1.12"
+
+// assertionFilter builds a filter asserting that the attributes modified by
+// the request still hold their prior values: each prior value is present and
+// attributes that had none are absent. Values added since are not detected,
+// as filters cannot tell that an attribute holds no other values. prior
+// returns the prior values of an attribute and whether they can be checked at
+// all; attributes that cannot are left out, as are those the schema, if any,
+// gives no equality rule, and binary values. It returns "" if there is
+// nothing to assert.
+func assertionFilter(modify *ldap.ModifyRequest, s *util.Schema, prior func(name string) ([]string, bool)) string {
+	seen := []string{}
+	filters := []string{}
+	for _, change := range modify.Changes {
+		name := change.Modification.Type
+		if containsEqualFold(seen, name) {
+			continue
+		}
+		seen = append(seen, name)
+		values, ok := prior(name)
+		if !ok || !hasEqualityRule(s, name) {
+			debugLog("ldap_object::assertion - not asserting the values of %q", name)
+			continue
+		}
+		text := []string{}
+		for _, v := range values {
+			if utf8.ValidString(v) {
+				text = append(text, v)
+			}
+		}
+		if len(values) > 0 && len(text) == 0 {
+			continue
+		}
+		values = text
+		if len(values) == 0 {
+			filters = append(filters, fmt.Sprintf("(!(%s=*))", name))
+			continue
+		}
+		for _, v := range values {
+			filters = append(filters, fmt.Sprintf("(%s=%s)", name, ldap.EscapeFilter(v)))
+		}
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	return "(&" + strings.Join(filters, "") + ")"
+}
+
+// hasEqualityRule tells whether the schema gives the attribute, or the one it
+// derives from, an equality rule, so that its values can be asserted;
+// attributes the schema does not know are assumed to have one.
+func hasEqualityRule(s *util.Schema, name string) bool {
+	if s == nil {
+		return true
+	}
+	// options like ";binary" are not part of the name of the type
+	name = strings.SplitN(name, ";", 2)[0]
+	d := s.AttributeType(name)
+	if d == nil {
+		return true
+	}
+	for seen := map[*util.SchemaDefinition]bool{}; d != nil && !seen[d]; {
+		if d.Equality != "" {
+			return true
+		}
+		seen[d] = true
+		if len(d.Sup) == 0 {
+			break
+		}
+		d = s.AttributeType(d.Sup[0])
+	}
+	return false
+}
+
+// assertionControl returns the critical control making the server perform
+// the request only if the entry matches the filter.
+func assertionControl(filter string) (ldap.Control, error) {
+	packet, err := ldap.CompileFilter(filter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Compiling assertion filter %q", filter)
+	}
+	return ldap.NewControlString(assertionControlOID, true, string(packet.Bytes())), nil
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/trevex/terraform-provider-ldap/util"
+)
+
+func TestAssertionFilter(t *testing.T) {
+	s := &util.Schema{AttributeTypes: []*util.SchemaDefinition{
+		{OID: "2.5.4.41", Names: []string{"name"}, Equality: "caseIgnoreMatch"},
+		{OID: "2.5.4.4", Names: []string{"sn"}, Sup: []string{"name"}},
+		{OID: "2.5.4.36", Names: []string{"userCertificate"}},
+	}}
+	prior := map[string][]string{
+		"sn":                     {"Doe"},
+		"description":            {"a", "b"},
+		"mail":                   {},
+		"userCertificate;binary": {"\x30\x82"},
+		"jpegPhoto":              {"\xff\xd8", "text"},
+	}
+	modify := ldap.NewModifyRequest("cn=jdoe,dc=example,dc=com", nil)
+	for _, name := range []string{"sn", "description", "mail", "userCertificate;binary", "jpegPhoto", "userPassword"} {
+		modify.Replace(name, []string{"new"})
+	}
+	filter := assertionFilter(modify, s, func(name string) ([]string, bool) {
+		values, ok := prior[name]
+		return values, ok
+	})
+	// sn inherits the equality rule of name, userCertificate has none, binary
+	// values are left out and userPassword has no prior values to check
+	expected := "(&(sn=Doe)(description=a)(description=b)(!(mail=*))(jpegPhoto=text))"
+	if filter != expected {
+		t.Errorf("Expected %q, got %q", expected, filter)
+	}
+
+	// without a schema, all attributes are asserted
+	modify = ldap.NewModifyRequest("cn=jdoe,dc=example,dc=com", nil)
+	modify.Replace("userCertificate;binary", []string{"new"})
+	modify.Replace("mail", []string{"new"})
+	filter = assertionFilter(modify, nil, func(name string) ([]string, bool) {
+		return nil, true
+	})
+	if expected := "(&(!(userCertificate;binary=*))(!(mail=*)))"; filter != expected {
+		t.Errorf("Expected %q, got %q", expected, filter)
+	}
+}
@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// the ways ldap_object owns an attribute
+const (
+	// the configured values are the only ones (the default)
+	policyAuthoritative = "authoritative"
+	// the configured values are added, other values are left alone
+	policyAdditive = "additive"
+	// the configured values are set when the object is created and ignored
+	// afterwards
+	policyCreateOnly = "create_only"
+)
+
+// the ways the values of changed attributes are modified
+const (
+	// delta for attributes with many values, replace otherwise
+	strategyAuto = "auto"
+	// all the values are sent
+	strategyReplace = "replace"
+	// only the added and removed values are sent
+	strategyDelta = "delta"
+)
+
+// the number of values from which the auto strategy sends deltas
+const deltaStrategyThreshold = 100
+
+func attributePolicySchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeSet,
+		Description: "How individual attributes (or objectClass, for object_classes) are owned and modified.",
+		Optional:    true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:        schema.TypeString,
+					Description: "The name of the attribute.",
+					Required:    true,
+				},
+				"mode": {
+					Type:         schema.TypeString,
+					Description:  "authoritative (the default) makes the configured values the only ones, additive adds them and leaves other values alone, create_only sets them when the object is created and ignores them afterwards; changing them in the configuration then leaves both the object and the state alone, so plans keep showing the change.",
+					Optional:     true,
+					Default:      policyAuthoritative,
+					ValidateFunc: validation.StringInSlice([]string{policyAuthoritative, policyAdditive, policyCreateOnly}, false),
+				},
+				"modify_strategy": {
+					Type:         schema.TypeString,
+					Description:  "Overrides the modify_strategy of the object for this attribute.",
+					Optional:     true,
+					ValidateFunc: validation.StringInSlice([]string{strategyAuto, strategyReplace, strategyDelta}, false),
+				},
+			},
+		},
+	}
+}
+
+// attributePolicies maps the lowercase names of the attributes in the
+// attribute_policy set to their modes.
+func attributePolicies(set *schema.Set) map[string]string {
+	policies := map[string]string{}
+	for _, p := range set.List() {
+		policy := p.(map[string]interface{})
+		policies[strings.ToLower(policy["name"].(string))] = policy["mode"].(string)
+	}
+	return policies
+}
+
+// attributePolicy returns the mode of an attribute.
+func attributePolicy(policies map[string]string, name string) string {
+	if mode, ok := policies[strings.ToLower(name)]; ok {
+		return mode
+	}
+	return policyAuthoritative
+}
+
+// attributeNamesWithPolicy returns the names of the attributes in the set
+// owned in the given mode.
+func attributeNamesWithPolicy(set *schema.Set, policies map[string]string, mode string) []string {
+	names := []string{}
+	for name := range attributeSetValues(set) {
+		if attributePolicy(policies, name) == mode {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modifyStrategies returns the modify strategy of each attribute of the
+// object.
+func modifyStrategies(d *schema.ResourceData) func(name string) string {
+	strategies := map[string]string{}
+	for _, p := range d.Get("attribute_policy").(*schema.Set).List() {
+		policy := p.(map[string]interface{})
+		if strategy := policy["modify_strategy"].(string); strategy != "" {
+			strategies[strings.ToLower(policy["name"].(string))] = strategy
+		}
+	}
+	strategy := d.Get("modify_strategy").(string)
+	return func(name string) string {
+		if s, ok := strategies[strings.ToLower(name)]; ok {
+			return s
+		}
+		return strategy
+	}
+}
+
+// useModifyDeltas checks whether only the changed values of an attribute are
+// to be sent; without a strategy, the auto one is used.
+func useModifyDeltas(strategy func(name string) string, name string, oldValues, newValues []string) bool {
+	s := strategyAuto
+	if strategy != nil {
+		s = strategy(name)
+	}
+	switch s {
+	case strategyDelta:
+		return true
+	case strategyReplace:
+		return false
+	}
+	return len(oldValues) >= deltaStrategyThreshold || len(newValues) >= deltaStrategyThreshold
+}
+
+// addValueDeltas deletes the values of an attribute that are gone and adds
+// the new ones.
+func addValueDeltas(modify *ldap.ModifyRequest, name string, oldValues, newValues []string) {
+	removed := []string{}
+	for _, v := range oldValues {
+		if !stringSliceContains(newValues, v) {
+			removed = append(removed, v)
+		}
+	}
+	added := []string{}
+	for _, v := range newValues {
+		if !stringSliceContains(oldValues, v) {
+			added = append(added, v)
+		}
+	}
+	if len(removed) > 0 {
+		debugLog("ldap_object::deltas - removing values %v from attribute %q", removed, name)
+		modify.Delete(name, removed)
+	}
+	if len(added) > 0 {
+		debugLog("ldap_object::deltas - adding values %v to attribute %q", added, name)
+		modify.Add(name, added)
+	}
+}
+
+// keepCreateOnlyValues returns the new attributes with the values of the
+// create-only attributes taken from the old ones.
+func keepCreateOnlyValues(oldSet, newSet *schema.Set, policies map[string]string) *schema.Set {
+	set := &schema.Set{F: attributeHash}
+	for _, s := range []*schema.Set{oldSet, newSet} {
+		for _, attribute := range s.List() {
+			for name := range attribute.(map[string]interface{}) {
+				if (attributePolicy(policies, name) == policyCreateOnly) == (s == oldSet) {
+					set.Add(attribute)
+				}
+			}
+		}
+	}
+	return set
+}
+
+// validateAttributePolicies checks that each attribute has a single policy.
+func validateAttributePolicies(set *schema.Set) error {
+	seen := map[string]bool{}
+	for _, p := range set.List() {
+		name := p.(map[string]interface{})["name"].(string)
+		if seen[strings.ToLower(name)] {
+			return fmt.Errorf("attribute_policy: %q has more than one policy", name)
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	return nil
+}
+
+// addAdditiveDeltas adds the values of an additively owned attribute that are
+// missing from the entry and deletes the ones dropped from the configuration,
+// leaving all other values alone.
+func addAdditiveDeltas(modify *ldap.ModifyRequest, entry *ldap.Entry, name string, oldValues, newValues []string) {
+	current := entry.GetEqualFoldAttributeValues(name)
+	added := []string{}
+	for _, v := range newValues {
+		if !stringSliceContains(current, v) {
+			added = append(added, v)
+		}
+	}
+	removed := []string{}
+	for _, v := range oldValues {
+		if !stringSliceContains(newValues, v) && stringSliceContains(current, v) {
+			removed = append(removed, v)
+		}
+	}
+	if len(added) > 0 {
+		debugLog("ldap_object::deltas - adding values %v to additive attribute %q", added, name)
+		modify.Add(name, added)
+	}
+	if len(removed) > 0 {
+		debugLog("ldap_object::deltas - removing values %v from additive attribute %q", removed, name)
+		modify.Delete(name, removed)
+	}
+}
+
+// lookupLDAPObject returns the entry of an object with its user attributes.
+func lookupLDAPObject(ctx context.Context, client *Client, dn string) (*ldap.Entry, error) {
+	request := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectclass=*)",
+		[]string{"*"},
+		nil,
+	)
+	sr, err := client.Search(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if len(sr.Entries) == 0 {
+		return nil, fmt.Errorf("Object %q not found", dn)
+	}
+	return sr.Entries[0], nil
+}
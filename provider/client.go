@@ -0,0 +1,324 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"github.com/trevex/terraform-provider-ldap/util"
+)
+
+// defaultTimeout is the default timeout of every CRUD operation of the
+// resources and data sources.
+const defaultTimeout = 5 * time.Minute
+
+// Client wraps the connection to the LDAP server and is handed to resources
+// and data sources as provider meta; its requests honour the context they are
+// issued with and carry the controls the provider and the context enable.
+type Client struct {
+	conn *ldap.Conn
+
+	baseDN                string
+	suffixes              []string
+	defaultSkipAttributes []string
+
+	ldifMutex   sync.Mutex
+	ldif        io.Writer
+	ldifStarted bool
+	dryRun      bool
+
+	transactions bool
+	writeOptions writeOptions
+
+	schemaMutex sync.Mutex
+	schema      *util.Schema
+
+	proxiedAuthorization string
+}
+
+// NewClient creates a client sending its requests over the given connection.
+func NewClient(conn *ldap.Conn) *Client {
+	return &Client{conn: conn}
+}
+
+// SetLDIFOutput makes the client write the requests changing the directory to
+// w, and, if dryRun is set, not send them at all.
+func (c *Client) SetLDIFOutput(w io.Writer, dryRun bool) {
+	c.ldifMutex.Lock()
+	defer c.ldifMutex.Unlock()
+	c.ldif = w
+	c.ldifStarted = false
+	c.dryRun = dryRun
+}
+
+// DryRun tells whether the requests changing the directory are not sent: the
+// resources then keep their planned state rather than reading it back.
+func (c *Client) DryRun() bool {
+	return c.dryRun
+}
+
+// SetDefaults sets the base DN relative DNs are expanded against and the
+// attributes no object tracks.
+func (c *Client) SetDefaults(baseDN string, skipAttributes []string) {
+	c.baseDN = baseDN
+	c.defaultSkipAttributes = skipAttributes
+}
+
+// ExpandDN turns a DN relative to the base DN into an absolute one.
+func (c *Client) ExpandDN(dn string) string {
+	return expandDN(dn, c.baseDN, c.suffixes)
+}
+
+// LoadSuffixes reads the suffixes of the directory (the naming contexts of
+// the root DSE), so that DNs located under any of them, and not only under
+// the base DN, are taken as absolute ones.
+func (c *Client) LoadSuffixes(ctx context.Context) error {
+	rootDSE, err := readRootDSE(ctx, c, "namingContexts")
+	if err != nil {
+		return err
+	}
+	c.suffixes = rootDSE.GetEqualFoldAttributeValues("namingContexts")
+	return nil
+}
+
+// Schema returns the schema of the directory, which is read once and then
+// kept until ResetSchema is called.
+func (c *Client) Schema(ctx context.Context) (*util.Schema, error) {
+	c.schemaMutex.Lock()
+	defer c.schemaMutex.Unlock()
+	if c.schema != nil {
+		return c.schema, nil
+	}
+	dn, err := lookupSubschemaDN(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	s, err := readLDAPSchema(ctx, c, dn)
+	if err != nil {
+		return nil, err
+	}
+	c.schema = s
+	return s, nil
+}
+
+// ResetSchema makes Schema read the schema again, e.g. after changing it.
+func (c *Client) ResetSchema() {
+	c.schemaMutex.Lock()
+	defer c.schemaMutex.Unlock()
+	c.schema = nil
+}
+
+// RelativeDN strips the base DN from a DN located under it.
+func (c *Client) RelativeDN(dn string) string {
+	return relativeDN(dn, c.baseDN)
+}
+
+// DefaultSkipAttributes returns the attributes no object tracks.
+func (c *Client) DefaultSkipAttributes() []string {
+	return c.defaultSkipAttributes
+}
+
+// Search performs a search request, limiting its time on the server to the
+// deadline of the context.
+func (c *Client) Search(ctx context.Context, request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	// the request of the caller is left alone, so that it can be retried
+	r := *request
+	if deadline, ok := ctx.Deadline(); ok {
+		// the time limit is given in seconds, where 0 means no limit
+		limit := int(time.Until(deadline) / time.Second)
+		if limit < 1 {
+			limit = 1
+		}
+		if r.TimeLimit == 0 || r.TimeLimit > limit {
+			r.TimeLimit = limit
+		}
+	}
+	r.Controls = append(append([]ldap.Control{}, request.Controls...), c.proxiedAuthorizationControls(ctx)...)
+
+	var result *ldap.SearchResult
+	err := c.do(ctx, func() (err error) {
+		result, err = c.conn.Search(&r)
+		return err
+	})
+	return result, err
+}
+
+// Add performs an add request.
+func (c *Client) Add(ctx context.Context, request *ldap.AddRequest) error {
+	r := *request
+	r.Controls = append(append([]ldap.Control{}, request.Controls...), c.writeControls(ctx, false)...)
+	r.Controls = append(r.Controls, c.proxiedAuthorizationControls(ctx)...)
+	if send, err := c.record(ctx, &r, r.Controls); !send || err != nil {
+		return err
+	}
+	r.Controls = append(r.Controls, transactionControls(ctx)...)
+	return c.do(ctx, func() error {
+		return c.conn.Add(&r)
+	})
+}
+
+// Modify performs a modify request.
+func (c *Client) Modify(ctx context.Context, request *ldap.ModifyRequest) error {
+	r := *request
+	r.Controls = append(append([]ldap.Control{}, request.Controls...), c.writeControls(ctx, true)...)
+	r.Controls = append(r.Controls, c.proxiedAuthorizationControls(ctx)...)
+	if send, err := c.record(ctx, &r, r.Controls); !send || err != nil {
+		return err
+	}
+	r.Controls = append(r.Controls, transactionControls(ctx)...)
+	return c.do(ctx, func() error {
+		return c.conn.Modify(&r)
+	})
+}
+
+// Del performs a delete request.
+func (c *Client) Del(ctx context.Context, request *ldap.DelRequest) error {
+	r := *request
+	r.Controls = append(append([]ldap.Control{}, request.Controls...), c.proxiedAuthorizationControls(ctx)...)
+	if send, err := c.record(ctx, &r, r.Controls); !send || err != nil {
+		return err
+	}
+	r.Controls = append(r.Controls, transactionControls(ctx)...)
+	return c.do(ctx, func() error {
+		return c.conn.Del(&r)
+	})
+}
+
+// ModifyDN performs a modify DN request.
+func (c *Client) ModifyDN(ctx context.Context, request *ldap.ModifyDNRequest) error {
+	r := *request
+	r.Controls = append(append([]ldap.Control{}, request.Controls...), c.proxiedAuthorizationControls(ctx)...)
+	if send, err := c.record(ctx, &r, r.Controls); !send || err != nil {
+		return err
+	}
+	r.Controls = append(r.Controls, transactionControls(ctx)...)
+	return c.do(ctx, func() error {
+		return c.conn.ModifyDN(&r)
+	})
+}
+
+// Extended performs an extended request. go-ldap expects a response name,
+// which many responses lack: if there is a value but no name, it takes the
+// value for the name, and if there is neither, it fails with a "malformed
+// extended response" error (see isEmptyExtendedResponse).
+func (c *Client) Extended(ctx context.Context, request *ldap.ExtendedRequest) (*ldap.ExtendedResponse, error) {
+	var response *ldap.ExtendedResponse
+	err := c.do(ctx, func() (err error) {
+		response, err = c.conn.Extended(request)
+		return err
+	})
+	return response, err
+}
+
+// isEmptyExtendedResponse tells whether go-ldap failed to decode an extended
+// response because it has neither a name nor a value. It only decodes
+// responses whose result code is success and reports the other result codes
+// as *ldap.Error; the other errors without a result code are those of
+// cancellation and of a connection that is closing.
+func (c *Client) isEmptyExtendedResponse(err error) bool {
+	var ldapErr *ldap.Error
+	return err != nil && !errors.As(err, &ldapErr) && !errors.Is(err, ldap.ErrNilConnection) &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !c.conn.IsClosing()
+}
+
+// Compare tells whether the attribute of an entry holds the value. go-ldap
+// sends compares without controls, so they fail if an identity is set rather
+// than being performed as the bind user.
+func (c *Client) Compare(ctx context.Context, dn, attribute, value string) (bool, error) {
+	if authzID := c.proxiedAuthorizationIdentity(ctx); authzID != "" {
+		return false, fmt.Errorf("Comparing %q: compares cannot be performed as %q, as they are sent without the Proxied Authorization control", dn, authzID)
+	}
+	var result bool
+	err := c.do(ctx, func() (err error) {
+		result, err = c.conn.Compare(dn, attribute, value)
+		return err
+	})
+	return result, err
+}
+
+// WhoAmI returns the authorization identity the requests are performed as
+// (RFC 4532), e.g. "dn:cn=admin,dc=example,dc=com", or "" if anonymous.
+func (c *Client) WhoAmI(ctx context.Context) (string, error) {
+	var result *ldap.WhoAmIResult
+	err := c.do(ctx, func() (err error) {
+		result, err = c.conn.WhoAmI(c.proxiedAuthorizationControls(ctx))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.AuthzID, nil
+}
+
+// record writes the request to the LDIF output, if any, and tells whether it
+// should be sent to the server; the output starts with the first record, and
+// the values of the sensitive attributes of the context are redacted.
+func (c *Client) record(ctx context.Context, request interface{}, controls []ldap.Control) (bool, error) {
+	c.ldifMutex.Lock()
+	defer c.ldifMutex.Unlock()
+	if c.ldif == nil {
+		return true, nil
+	}
+	record := ldifRecordFromRequest(request)
+	redacted := append(append([]string{}, ldifRedactedAttributes...), sensitiveAttributeNames(ctx)...)
+	output := "\n" + record.render(controls, redacted)
+	if !c.ldifStarted {
+		output = "version: 1\n" + output
+	}
+	if _, err := io.WriteString(c.ldif, output); err != nil {
+		return false, fmt.Errorf("Writing LDIF output for %q: %v", record.DN, err)
+	}
+	c.ldifStarted = true
+	if c.dryRun {
+		infoLog("dry run - not sending the %s request for %q", record.ChangeType, record.DN)
+		return false, nil
+	}
+	return true, nil
+}
+
+// do runs the request in the background and waits for it to complete or for
+// the context to be done, whichever comes first; in the latter case the
+// response is discarded whenever it arrives.
+func (c *Client) do(ctx context.Context, request func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- request()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		warnLog("ldap request abandoned: %v", ctx.Err())
+		return ctx.Err()
+	}
+}
+
+// readRootDSE reads the given attributes of the root DSE.
+func readRootDSE(ctx context.Context, client *Client, attributes ...string) (*ldap.Entry, error) {
+	request := ldap.NewSearchRequest(
+		"",
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectClass=*)",
+		attributes,
+		nil,
+	)
+	sr, err := client.Search(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if len(sr.Entries) == 0 {
+		return nil, fmt.Errorf("The root DSE is not visible")
+	}
+	return sr.Entries[0], nil
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestClientCancelsRequests(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	client := testProviderMeta(t, s).(*Client)
+	s.DelaySearches(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	request := ldap.NewSearchRequest(testBaseDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false, "(objectClass=*)", nil, nil)
+	if _, err := client.Search(ctx, request); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the search to time out, got %v", err)
+	}
+	if s.TimeLimit() != 1 {
+		t.Errorf("Expected the deadline to limit the search, got %d", s.TimeLimit())
+	}
+	if request.TimeLimit != 0 || len(request.Controls) != 0 {
+		t.Errorf("Expected the request to be left alone, got time limit %d and controls %v", request.TimeLimit, request.Controls)
+	}
+}
+
+func TestClientLeavesRequestsAlone(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	client := testProviderMeta(t, s).(*Client)
+	client.SetWriteOptions(true, false)
+	client.SetProxiedAuthorization("dn:" + testBaseDN)
+
+	// the controls are added to a copy of the request, so that it can be
+	// sent again without carrying them twice
+	request := ldap.NewModifyRequest(testBaseDN, nil)
+	request.Replace("description", []string{"a"})
+	for i := 0; i < 2; i++ {
+		if err := client.Modify(context.Background(), request); err != nil {
+			t.Fatalf("Unable to modify %q: %v", testBaseDN, err)
+		}
+		if len(request.Controls) != 0 {
+			t.Fatalf("Expected the request to be left alone, got controls %v", request.Controls)
+		}
+	}
+}
+
+func TestClientCachesSchema(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	s.SetObjectClasses("( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) )")
+	client := testProviderMeta(t, s).(*Client)
+
+	first, err := client.Schema(context.Background())
+	if err != nil {
+		t.Fatalf("Unable to read the schema: %v", err)
+	}
+	if second, _ := client.Schema(context.Background()); second != first {
+		t.Errorf("Expected the schema to be read once")
+	}
+	client.ResetSchema()
+	if third, _ := client.Schema(context.Background()); third == first {
+		t.Errorf("Expected the schema to be read again after resetting it")
+	}
+}
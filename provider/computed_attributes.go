@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// readComputedAttributes looks up the given operational or server-generated
+// attributes ("+" for all operational ones) of an object and returns their
+// JSON-encoded values by attribute name. They are searched for on their own,
+// so that operational attributes are never mistaken for user attributes.
+func readComputedAttributes(ctx context.Context, client *Client, dn string, names []string) (map[string]string, error) {
+	request := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectclass=*)",
+		names,
+		nil,
+	)
+	sr, err := client.Search(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if len(sr.Entries) == 0 {
+		return nil, fmt.Errorf("Entry %q not found", dn)
+	}
+
+	computed := make(map[string]string)
+	for _, attribute := range sr.Entries[0].Attributes {
+		if !containsEqualFold(names, "+") && !containsEqualFold(names, attribute.Name) {
+			continue
+		}
+		values := []string{}
+		for _, value := range attribute.ByteValues {
+			values = append(values, formatComputedValue(attribute.Name, value))
+		}
+		jsonBytes, err := json.Marshal(values)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Marshalling attribute %s values", attribute.Name)
+		}
+		computed[attribute.Name] = string(jsonBytes)
+	}
+	return computed, nil
+}
+
+// formatComputedValue renders a value as text: Active Directory GUIDs and
+// SIDs get their usual string forms, other binary values are base64-encoded.
+func formatComputedValue(name string, value []byte) string {
+	switch {
+	case strings.EqualFold(name, "objectGUID") && len(value) == 16:
+		return formatObjectGUID(value)
+	case strings.EqualFold(name, "objectSid") && len(value) >= 8 && len(value) == 8+4*int(value[1]):
+		return formatObjectSid(value)
+	case !utf8.Valid(value):
+		return base64.StdEncoding.EncodeToString(value)
+	}
+	return string(value)
+}
+
+// formatObjectGUID renders a GUID, whose first three groups are stored in
+// little-endian order, e.g. "6f9619ff-8b86-d011-b42d-00c04fc964ff".
+func formatObjectGUID(b []byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10],
+		b[10:16])
+}
+
+// formatObjectSid renders a security identifier, e.g. "S-1-5-21-...-1104":
+// the revision, the big-endian 48-bit authority and the little-endian
+// sub-authorities.
+func formatObjectSid(b []byte) string {
+	var authority uint64
+	for _, c := range b[2:8] {
+		authority = authority<<8 | uint64(c)
+	}
+	sid := fmt.Sprintf("S-%d-%d", b[0], authority)
+	for i := 0; i < int(b[1]); i++ {
+		sid += fmt.Sprintf("-%d", binary.LittleEndian.Uint32(b[8+4*i:]))
+	}
+	return sid
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFormatComputedValue(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value []byte
+		want  string
+	}{
+		{"objectGUID", []byte{0xff, 0x19, 0x96, 0x6f, 0x86, 0x8b, 0x11, 0xd0, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff}, "6f9619ff-8b86-d011-b42d-00c04fc964ff"},
+		{"objectSid", []byte{1, 2, 0, 0, 0, 0, 0, 5, 21, 0, 0, 0, 0x50, 0x04, 0, 0}, "S-1-5-21-1104"},
+		{"objectSid", []byte{1, 5, 0xff}, "AQX/"},
+		{"entryUUID", []byte("8a6c3a6e-5b0e-103c-8f7a-d5e4c2e9d1a0"), "8a6c3a6e-5b0e-103c-8f7a-d5e4c2e9d1a0"},
+		{"msExchMailboxGuid", []byte{0xff, 0xfe}, "//4="},
+	}
+	for _, tc := range testCases {
+		if got := formatComputedValue(tc.name, tc.value); got != tc.want {
+			t.Errorf("Expected %s %v to be formatted as %q, got %q", tc.name, tc.value, tc.want, got)
+		}
+	}
+}
+
+func TestLDAPObjectComputedAttributes(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+
+	config := map[string]interface{}{
+		"dn":                  dn,
+		"object_classes":      []interface{}{"inetOrgPerson"},
+		"attributes":          testAttributesConfig(map[string][]string{"sn": {"Doe"}}),
+		"computed_attributes": []interface{}{"entryUUID"},
+	}
+	state := testApply(t, r, nil, config, meta)
+	computed := r.Data(state).Get("computed_attributes_json").(map[string]interface{})
+	want, _ := json.Marshal(s.Values(dn, "entryUUID"))
+	if len(computed) != 1 || computed["entryUUID"] != string(want) {
+		t.Errorf("Expected only the entryUUID %s, got %v", want, computed)
+	}
+	if _, ok := testAttributes(r, state)["entryUUID"]; ok {
+		t.Errorf("Expected the computed attributes not to be among the attributes")
+	}
+
+	// all operational attributes
+	config["computed_attributes"] = []interface{}{"+"}
+	state = testApply(t, r, state, config, meta)
+	computed = r.Data(state).Get("computed_attributes_json").(map[string]interface{})
+	for _, name := range []string{"entryUUID", "createTimestamp", "modifyTimestamp"} {
+		if _, ok := computed[name]; !ok {
+			t.Errorf("Expected %s among the computed attributes, got %v", name, computed)
+		}
+	}
+	if _, ok := computed["sn"]; ok {
+		t.Errorf("Expected only operational attributes, got %v", computed)
+	}
+
+	// changes of the computed attributes don't cause a diff
+	s.SetAttribute(dn, "modifyTimestamp", "20300101000000Z")
+	state = testRefresh(t, r, state, meta)
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes for computed attributes")
+	}
+}
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// the OIDs of the controls changing how the server applies writes
+const (
+	// adding existing values and deleting missing ones succeeds
+	permissiveModifyControlOID = "1.2.840.113556.1.4.1413"
+	// OpenLDAP relaxes its schema and NO-USER-MODIFICATION rules
+	relaxRulesControlOID = "1.3.6.1.4.1.4203.666.5.12"
+	// the request is performed as the given identity (RFC 4370)
+	proxiedAuthorizationControlOID = "2.16.840.1.113730.3.4.18"
+)
+
+// writeOptions are the controls to send with the write requests.
+type writeOptions struct {
+	permissiveModify bool
+	relaxRules       bool
+}
+
+// writeOptionsKey is the key of the write options of a resource in the
+// context of its requests.
+type writeOptionsKey struct{}
+
+// SetWriteOptions makes the client send the Permissive Modify control with
+// every modify request and the Relax Rules control with every add and modify
+// request.
+func (c *Client) SetWriteOptions(permissiveModify, relaxRules bool) {
+	c.writeOptions = writeOptions{permissiveModify: permissiveModify, relaxRules: relaxRules}
+}
+
+func permissiveModifySchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Description: "Send modifies with the Permissive Modify control, so that adding values which exist already and deleting values which are gone succeed, e.g. when others change the same attributes; the provider option enables it for all resources.",
+		Optional:    true,
+		Default:     false,
+	}
+}
+
+func relaxRulesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Description: "Send adds and modifies with the (OpenLDAP) Relax Rules control, so that operational and NO-USER-MODIFICATION attributes can be set, e.g. during migrations; the provider option enables it for all resources.",
+		Optional:    true,
+		Default:     false,
+	}
+}
+
+// withWriteOptions returns the context to issue the write requests of a
+// resource with, according to its options.
+func withWriteOptions(ctx context.Context, d *schema.ResourceData) context.Context {
+	return context.WithValue(ctx, writeOptionsKey{}, writeOptions{
+		permissiveModify: d.Get("permissive_modify").(bool),
+		relaxRules:       d.Get("relax_rules").(bool),
+	})
+}
+
+// writeControls returns the controls enabled by the client or the context
+// for an add request or, if modify is set, a modify request.
+func (c *Client) writeControls(ctx context.Context, modify bool) []ldap.Control {
+	options, _ := ctx.Value(writeOptionsKey{}).(writeOptions)
+	controls := []ldap.Control{}
+	if modify && (c.writeOptions.permissiveModify || options.permissiveModify) {
+		controls = append(controls, ldap.NewControlString(permissiveModifyControlOID, false, ""))
+	}
+	if c.writeOptions.relaxRules || options.relaxRules {
+		controls = append(controls, ldap.NewControlString(relaxRulesControlOID, true, ""))
+	}
+	return controls
+}
+
+// proxiedAuthorizationKey is the key of the identity a resource acts as in
+// the context of its requests.
+type proxiedAuthorizationKey struct{}
+
+// SetProxiedAuthorization makes the client send every request on behalf of
+// the given authorization identity ("dn:..." or "u:..."), unless it is empty.
+func (c *Client) SetProxiedAuthorization(authzID string) {
+	c.proxiedAuthorization = authzID
+}
+
+func proxiedAuthorizationSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Description:  "The authorization identity (`dn:<DN>` or `u:<user>`) to perform the requests of this resource as, with the Proxied Authorization control (RFC 4370), so that its access rights apply; it overrides the one of the provider. Imports, which know nothing of the configuration, are performed as the identity of the provider.",
+		Optional:     true,
+		ValidateFunc: validateAuthzID,
+	}
+}
+
+// validateAuthzID checks that an authorization identity has one of the forms
+// of RFC 4513.
+func validateAuthzID(v interface{}, k string) ([]string, []error) {
+	authzID := v.(string)
+	if authzID != "" && !strings.HasPrefix(authzID, "dn:") && !strings.HasPrefix(authzID, "u:") {
+		return nil, []error{fmt.Errorf("%s: %q is neither of the form dn:<DN> nor u:<user>", k, authzID)}
+	}
+	return nil, nil
+}
+
+// withProxiedAuthorization returns the context to issue the requests of a
+// resource with, if it overrides the identity of the provider; d is its data
+// or, when planning, its diff.
+func withProxiedAuthorization(ctx context.Context, d interface{ Get(string) interface{} }) context.Context {
+	if authzID := d.Get("proxied_authorization").(string); authzID != "" {
+		return context.WithValue(ctx, proxiedAuthorizationKey{}, authzID)
+	}
+	return ctx
+}
+
+// proxiedAuthorizationControls returns the control making a request be
+// performed as the identity of the context or else of the client, if any.
+func (c *Client) proxiedAuthorizationControls(ctx context.Context) []ldap.Control {
+	authzID := c.proxiedAuthorizationIdentity(ctx)
+	if authzID == "" {
+		return nil
+	}
+	return []ldap.Control{ldap.NewControlString(proxiedAuthorizationControlOID, true, authzID)}
+}
+
+// proxiedAuthorizationIdentity returns the identity of the context or else of
+// the client, if any.
+func (c *Client) proxiedAuthorizationIdentity(ctx context.Context) string {
+	if authzID, ok := ctx.Value(proxiedAuthorizationKey{}).(string); ok {
+		return authzID
+	}
+	return c.proxiedAuthorization
+}
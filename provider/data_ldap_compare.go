@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataLDAPCompare() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataLDAPCompareRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Description: "The `ldap_compare` data source checks whether an attribute of an entry holds a value with an LDAP compare, which is cheaper than reading the entry and may be allowed where reading is not. Compares are sent without controls, so it fails if the provider sets `proxied_authorization`.",
+
+		Schema: map[string]*schema.Schema{
+			"dn": {
+				Type:        schema.TypeString,
+				Description: "The DN of the entry.",
+				Required:    true,
+			},
+			"attribute": {
+				Type:        schema.TypeString,
+				Description: "The name of the attribute, e.g. memberOf.",
+				Required:    true,
+			},
+			"value": {
+				Type:        schema.TypeString,
+				Description: "The value to look for, compared according to the equality matching rule of the attribute.",
+				Required:    true,
+			},
+			"fail_if_false": {
+				Type:        schema.TypeBool,
+				Description: "Fail (and with it the plan) if the attribute doesn't hold the value. Alternatively, `result` can be checked by a `precondition` block (Terraform 1.2 and later) of the data source or of the resources depending on it, to fail with a message of your own.",
+				Optional:    true,
+				Default:     false,
+			},
+			"result": {
+				Type:        schema.TypeBool,
+				Description: "Whether the attribute holds the value.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataLDAPCompareRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	dn := client.ExpandDN(d.Get("dn").(string))
+	attribute := d.Get("attribute").(string)
+	value := d.Get("value").(string)
+
+	debugLog("ldap_compare::read - comparing %s of %q", attribute, dn)
+	result, err := client.Compare(ctx, dn, attribute, value)
+	// an entry without the attribute doesn't hold the value either
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchAttribute) {
+		return ldapDiagnostics(err, dn, nil)
+	}
+	debugLog("ldap_compare::read - %s of %q holds the value: %v", attribute, dn, result)
+
+	if !result && d.Get("fail_if_false").(bool) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Comparison failed on %q", dn),
+			Detail:   fmt.Sprintf("The attribute %s of %q does not hold the value %q.", attribute, dn, value),
+		}}
+	}
+
+	d.SetId(fmt.Sprintf("%s|%s=%s", dn, attribute, value))
+	d.Set("result", result)
+	return nil
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestDataLDAPCompareRead(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	testSeedPeople(s)
+	meta := testProviderMeta(t, s)
+	r := dataLDAPCompare()
+	dn := "cn=jdoe,ou=people," + testBaseDN
+
+	for _, c := range []struct {
+		attribute, value string
+		result           bool
+	}{
+		{"mail", "john.doe@example.com", true},
+		{"mail", "jdoe@example.org", false},
+		{"employeeType", "staff", false},
+	} {
+		d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+			"dn":        dn,
+			"attribute": c.attribute,
+			"value":     c.value,
+		})
+		if diags := dataLDAPCompareRead(context.Background(), d, meta); diags.HasError() {
+			t.Fatalf("Unexpected error: %v", diags)
+		}
+		if result := d.Get("result").(bool); result != c.result {
+			t.Errorf("Expected %s=%s to be %v, got %v", c.attribute, c.value, c.result, result)
+		}
+	}
+
+	// a false result can fail the read
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"dn":            dn,
+		"attribute":     "mail",
+		"value":         "jdoe@example.org",
+		"fail_if_false": true,
+	})
+	if diags := dataLDAPCompareRead(context.Background(), d, meta); !diags.HasError() {
+		t.Errorf("Expected an error for a false comparison")
+	}
+
+	// as does a missing entry
+	d = schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"dn":        "cn=missing,ou=people," + testBaseDN,
+		"attribute": "mail",
+		"value":     "jdoe@example.com",
+	})
+	if diags := dataLDAPCompareRead(context.Background(), d, meta); !diags.HasError() {
+		t.Errorf("Expected an error for a missing entry")
+	}
+
+	// compares can't be performed as another identity
+	meta.(*Client).SetProxiedAuthorization("dn:uid=delegate," + testBaseDN)
+	d = schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"dn":        dn,
+		"attribute": "mail",
+		"value":     "john.doe@example.com",
+	})
+	if diags := dataLDAPCompareRead(context.Background(), d, meta); !diags.HasError() {
+		t.Errorf("Expected an error comparing with an identity set")
+	}
+}
@@ -3,11 +3,13 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
 )
@@ -52,7 +54,11 @@ func depthHelpString() string {
 
 func dataLDAPObject() *schema.Resource {
 	return &schema.Resource{
-		Read: dataLDAPObjectRead,
+		ReadContext: dataLDAPObjectRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(defaultTimeout),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"base_dn": {
@@ -126,13 +132,13 @@ func dataLDAPObject() *schema.Resource {
 	}
 }
 
-func dataLDAPObjectRead(d *schema.ResourceData, meta interface{}) error {
-	return searchLDAPObject(d, meta)
+func dataLDAPObjectRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	return diag.FromErr(searchLDAPObject(ctx, d, meta))
 }
 
-func searchLDAPObject(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*ldap.Conn)
-	baseDN := d.Get("base_dn").(string)
+func searchLDAPObject(ctx context.Context, d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*Client)
+	baseDN := client.ExpandDN(d.Get("base_dn").(string))
 	searchDepthInput := d.Get("depth").(string)
 	searchDepth := normalizeSearchDepth(searchDepthInput)
 
@@ -165,7 +171,7 @@ func searchLDAPObject(d *schema.ResourceData, meta interface{}) error {
 		nil,                    // controls
 	)
 
-	searchResult, err := client.Search(request)
+	searchResult, err := client.Search(ctx, request)
 	if err != nil {
 		if err, ok := err.(*ldap.Error); ok {
 			if err.ResultCode == 32 { // no such object
@@ -190,7 +196,7 @@ func searchLDAPObject(d *schema.ResourceData, meta interface{}) error {
 	foundObject := searchResult.Entries[0]
 
 	dn := foundObject.DN
-	
+
 	if dn == "" {
 		for _, key := range []string{"dn", "DN", "distinguished_name", "distinguishedName"} {
 			dn = foundObject.GetAttributeValue(key)
@@ -217,7 +223,7 @@ func searchLDAPObject(d *schema.ResourceData, meta interface{}) error {
 	}
 
 	// retrieve attributes to skip from HCL
-	attributesToSkip := []string{}
+	attributesToSkip := append([]string{}, client.DefaultSkipAttributes()...)
 	for _, attr := range (d.Get("skip_attributes").(*schema.Set)).List() {
 		debugLog("data.ldap_object::create - object %q set to skip: %q", dn, attr.(string))
 		attributesToSkip = append(attributesToSkip, attr.(string))
@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func testSeedPeople(s *testLDAPServer) {
+	s.PutEntry("ou=people,"+testBaseDN, map[string][]string{
+		"objectClass": {"organizationalUnit"},
+		"ou":          {"people"},
+	})
+	s.PutEntry("cn=jdoe,ou=people,"+testBaseDN, map[string][]string{
+		"objectClass": {"inetOrgPerson"},
+		"cn":          {"jdoe"},
+		"sn":          {"Doe"},
+		"mail":        {"jdoe@example.com", "john.doe@example.com"},
+	})
+	s.PutEntry("cn=asmith,ou=people,"+testBaseDN, map[string][]string{
+		"objectClass": {"inetOrgPerson"},
+		"cn":          {"asmith"},
+		"sn":          {"Smith"},
+	})
+}
+
+func TestDataLDAPObjectRead(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	testSeedPeople(s)
+	meta := testProviderMeta(t, s)
+	r := dataLDAPObject()
+
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"base_dn":           testBaseDN,
+		"search_values":     map[string]interface{}{"sn": "Doe"},
+		"select_attributes": []interface{}{"mail", "sn"},
+	})
+	if diags := dataLDAPObjectRead(context.Background(), d, meta); diags.HasError() {
+		t.Fatalf("Unexpected error: %v", diags)
+	}
+	if dn := d.Get("dn").(string); dn != "cn=jdoe,ou=people,"+testBaseDN {
+		t.Errorf("Unexpected DN %q", dn)
+	}
+	if n := d.Get("attributes").(*schema.Set).Len(); n != 3 {
+		t.Errorf("Expected 3 selected values, got %d", n)
+	}
+	if mail := d.Get("attributes_json.mail").(string); mail != `["jdoe@example.com","john.doe@example.com"]` {
+		t.Errorf("Unexpected mail values %s", mail)
+	}
+
+	// more than one match
+	d = schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"base_dn":       testBaseDN,
+		"search_values": map[string]interface{}{"objectClass": "inetOrgPerson"},
+	})
+	if diags := dataLDAPObjectRead(context.Background(), d, meta); !diags.HasError() {
+		t.Errorf("Expected an error for several matches")
+	}
+
+	// no match
+	d = schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"base_dn":       testBaseDN,
+		"depth":         "one",
+		"search_values": map[string]interface{}{"sn": "Doe"},
+	})
+	if diags := dataLDAPObjectRead(context.Background(), d, meta); !diags.HasError() {
+		t.Errorf("Expected an error if nothing matches")
+	}
+}
+
+func TestAccDataLDAPObject(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	testSeedPeople(s)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(s) + fmt.Sprintf(`
+data "ldap_object" "jdoe" {
+  base_dn           = %q
+  search_values     = { sn = "Doe" }
+  select_attributes = ["mail"]
+}
+`, testBaseDN),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.ldap_object.jdoe", "dn", "cn=jdoe,ou=people,"+testBaseDN),
+					resource.TestCheckResourceAttr("data.ldap_object.jdoe", "attributes.#", "2"),
+					resource.TestCheckResourceAttr("data.ldap_object.jdoe", "attributes_json.mail", `["jdoe@example.com","john.doe@example.com"]`),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/trevex/terraform-provider-ldap/util"
+)
+
+// the attributes of the subschema entry that hold the schema definitions
+const (
+	attributeTypesAttribute = "attributeTypes"
+	objectClassesAttribute  = "objectClasses"
+	ldapSyntaxesAttribute   = "ldapSyntaxes"
+	matchingRulesAttribute  = "matchingRules"
+)
+
+func dataLDAPSchema() *schema.Resource {
+	stringList := func(description string) *schema.Schema {
+		return &schema.Schema{
+			Type:        schema.TypeList,
+			Description: description,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		}
+	}
+	computedString := func(description string) *schema.Schema {
+		return &schema.Schema{
+			Type:        schema.TypeString,
+			Description: description,
+			Computed:    true,
+		}
+	}
+	computedBool := func(description string) *schema.Schema {
+		return &schema.Schema{
+			Type:        schema.TypeBool,
+			Description: description,
+			Computed:    true,
+		}
+	}
+
+	return &schema.Resource{
+		ReadContext: dataLDAPSchemaRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Description: "The `ldap_schema` data source reads the subschema entry advertised by the server and exposes its attribute types, object classes, syntaxes and matching rules.",
+
+		Schema: map[string]*schema.Schema{
+			"dn": {
+				Type:        schema.TypeString,
+				Description: "The DN of the subschema entry; if not set, it is looked up via the subschemaSubentry attribute of the root DSE.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"attribute_types": {
+				Type:        schema.TypeList,
+				Description: "The attribute types defined in the schema.",
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"oid":                  computedString("The OID of the attribute type."),
+						"names":                stringList("The names of the attribute type."),
+						"description":          computedString("The description of the attribute type."),
+						"obsolete":             computedBool("Whether the attribute type is obsolete."),
+						"sup":                  stringList("The attribute type this one is derived from."),
+						"syntax":               computedString("The syntax OID, possibly including a length bound."),
+						"equality":             computedString("The equality matching rule."),
+						"ordering":             computedString("The ordering matching rule."),
+						"substr":               computedString("The substrings matching rule."),
+						"single_value":         computedBool("Whether the attribute may only hold a single value."),
+						"collective":           computedBool("Whether the attribute is collective."),
+						"no_user_modification": computedBool("Whether the attribute cannot be modified by users."),
+						"usage":                computedString("The usage of the attribute type (e.g. userApplications, directoryOperation)."),
+					},
+				},
+			},
+			"object_classes": {
+				Type:        schema.TypeList,
+				Description: "The object classes defined in the schema.",
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"oid":         computedString("The OID of the object class."),
+						"names":       stringList("The names of the object class."),
+						"description": computedString("The description of the object class."),
+						"obsolete":    computedBool("Whether the object class is obsolete."),
+						"sup":         stringList("The superclasses of the object class."),
+						"kind":        computedString("The kind of object class: STRUCTURAL, AUXILIARY or ABSTRACT."),
+						"must":        stringList("The attributes an entry of this class must have."),
+						"may":         stringList("The attributes an entry of this class may have."),
+					},
+				},
+			},
+			"ldap_syntaxes": {
+				Type:        schema.TypeList,
+				Description: "The LDAP syntaxes known to the server.",
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"oid":         computedString("The OID of the syntax."),
+						"description": computedString("The description of the syntax."),
+					},
+				},
+			},
+			"matching_rules": {
+				Type:        schema.TypeList,
+				Description: "The matching rules known to the server.",
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"oid":         computedString("The OID of the matching rule."),
+						"names":       stringList("The names of the matching rule."),
+						"description": computedString("The description of the matching rule."),
+						"obsolete":    computedBool("Whether the matching rule is obsolete."),
+						"syntax":      computedString("The syntax OID of the assertion value."),
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataLDAPSchemaRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+
+	dn := d.Get("dn").(string)
+	if dn == "" {
+		var err error
+		if dn, err = lookupSubschemaDN(ctx, client); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	s, err := readLDAPSchema(ctx, client, dn)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	attributeTypes := make([]interface{}, 0, len(s.AttributeTypes))
+	for _, at := range s.AttributeTypes {
+		attributeTypes = append(attributeTypes, map[string]interface{}{
+			"oid":                  at.OID,
+			"names":                at.Names,
+			"description":          at.Description,
+			"obsolete":             at.Obsolete,
+			"sup":                  at.Sup,
+			"syntax":               at.Syntax,
+			"equality":             at.Equality,
+			"ordering":             at.Ordering,
+			"substr":               at.Substr,
+			"single_value":         at.SingleValue,
+			"collective":           at.Collective,
+			"no_user_modification": at.NoUserModification,
+			"usage":                at.Usage,
+		})
+	}
+
+	objectClasses := make([]interface{}, 0, len(s.ObjectClasses))
+	for _, oc := range s.ObjectClasses {
+		objectClasses = append(objectClasses, map[string]interface{}{
+			"oid":         oc.OID,
+			"names":       oc.Names,
+			"description": oc.Description,
+			"obsolete":    oc.Obsolete,
+			"sup":         oc.Sup,
+			"kind":        oc.Kind,
+			"must":        oc.Must,
+			"may":         oc.May,
+		})
+	}
+
+	ldapSyntaxes := make([]interface{}, 0, len(s.LDAPSyntaxes))
+	for _, ls := range s.LDAPSyntaxes {
+		ldapSyntaxes = append(ldapSyntaxes, map[string]interface{}{
+			"oid":         ls.OID,
+			"description": ls.Description,
+		})
+	}
+
+	matchingRules := make([]interface{}, 0, len(s.MatchingRules))
+	for _, mr := range s.MatchingRules {
+		matchingRules = append(matchingRules, map[string]interface{}{
+			"oid":         mr.OID,
+			"names":       mr.Names,
+			"description": mr.Description,
+			"obsolete":    mr.Obsolete,
+			"syntax":      mr.Syntax,
+		})
+	}
+
+	d.SetId(dn)
+	d.Set("dn", dn)
+	for k, v := range map[string]interface{}{
+		"attribute_types": attributeTypes,
+		"object_classes":  objectClasses,
+		"ldap_syntaxes":   ldapSyntaxes,
+		"matching_rules":  matchingRules,
+	} {
+		if err := d.Set(k, v); err != nil {
+			warnLog("data.ldap_schema::read - error setting %s for %q : %v", k, dn, err)
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
+
+// lookupSubschemaDN reads the subschemaSubentry attribute of the root DSE to
+// find the entry holding the schema definitions.
+func lookupSubschemaDN(ctx context.Context, client *Client) (string, error) {
+	rootDSE, err := readRootDSE(ctx, client, "subschemaSubentry")
+	if err != nil {
+		debugLog("ldap_schema - lookup of the root DSE returned an error %v", err)
+		return "", err
+	}
+	dn := rootDSE.GetEqualFoldAttributeValue("subschemaSubentry")
+	if dn == "" {
+		return "", fmt.Errorf("The root DSE does not advertise a subschemaSubentry")
+	}
+	debugLog("ldap_schema - subschema entry is %q", dn)
+	return dn, nil
+}
+
+// readLDAPSchema reads and parses the schema definitions of the given
+// subschema entry.
+func readLDAPSchema(ctx context.Context, client *Client, dn string) (*util.Schema, error) {
+	request := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectClass=subschema)",
+		[]string{attributeTypesAttribute, objectClassesAttribute, ldapSyntaxesAttribute, matchingRulesAttribute},
+		nil,
+	)
+
+	sr, err := client.Search(ctx, request)
+	if err != nil {
+		debugLog("ldap_schema - lookup for %q returned an error %v", dn, err)
+		return nil, err
+	}
+	if len(sr.Entries) == 0 {
+		return nil, fmt.Errorf("Subschema entry %q not found", dn)
+	}
+
+	entry := sr.Entries[0]
+	s := &util.Schema{}
+	for _, target := range []struct {
+		attribute   string
+		definitions *[]*util.SchemaDefinition
+	}{
+		{attributeTypesAttribute, &s.AttributeTypes},
+		{objectClassesAttribute, &s.ObjectClasses},
+		{ldapSyntaxesAttribute, &s.LDAPSyntaxes},
+		{matchingRulesAttribute, &s.MatchingRules},
+	} {
+		definitions, err := util.ParseSchemaDefinitions(entry.GetEqualFoldAttributeValues(target.attribute))
+		if err != nil {
+			return nil, fmt.Errorf("Parsing %s of %q: %v", target.attribute, dn, err)
+		}
+		*target.definitions = definitions
+	}
+
+	debugLog("ldap_schema - read %d attribute types and %d object classes from %q", len(s.AttributeTypes), len(s.ObjectClasses), dn)
+	return s, nil
+}
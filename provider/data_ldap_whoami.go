@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataLDAPWhoAmI() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataLDAPWhoAmIRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Description: "The `ldap_whoami` data source returns the authorization identity the provider operates as, according to the server (RFC 4532 Who am I? operation), e.g. to debug access rights when proxied authorization or SASL are involved.",
+
+		Schema: map[string]*schema.Schema{
+			"proxied_authorization": proxiedAuthorizationSchema(),
+			"authz_id": {
+				Type:        schema.TypeString,
+				Description: "The authorization identity, e.g. `dn:cn=admin,dc=example,dc=com` or `u:jdoe`; empty if anonymous.",
+				Computed:    true,
+			},
+			"dn": {
+				Type:        schema.TypeString,
+				Description: "The DN of the identity, if it is given as one.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataLDAPWhoAmIRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+
+	authzID, err := client.WhoAmI(ctx)
+	if err != nil {
+		return ldapDiagnostics(err, "", nil)
+	}
+	debugLog("ldap_whoami::read - operating as %q", authzID)
+
+	d.SetId(authzID)
+	if authzID == "" {
+		d.SetId("anonymous")
+	}
+	d.Set("authz_id", authzID)
+	if strings.HasPrefix(authzID, "dn:") {
+		d.Set("dn", strings.TrimPrefix(authzID, "dn:"))
+	} else {
+		d.Set("dn", "")
+	}
+	return nil
+}
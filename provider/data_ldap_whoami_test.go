@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestDataLDAPWhoAmIRead(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	delegate := "uid=delegate," + testBaseDN
+	s.PutEntry(delegate, map[string][]string{"objectClass": {"account"}, "uid": {"delegate"}})
+	meta := testProviderMeta(t, s)
+	r := dataLDAPWhoAmI()
+
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{})
+	if diags := dataLDAPWhoAmIRead(context.Background(), d, meta); diags.HasError() {
+		t.Fatalf("Unexpected error: %v", diags)
+	}
+	if authzID := d.Get("authz_id").(string); authzID != "dn:"+s.BindDN {
+		t.Errorf("Unexpected identity %q", authzID)
+	}
+
+	// the proxied identity is the one in effect
+	d = schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"proxied_authorization": "u:delegate",
+	})
+	if diags := dataLDAPWhoAmIRead(context.Background(), d, meta); diags.HasError() {
+		t.Fatalf("Unexpected error: %v", diags)
+	}
+	if dn := d.Get("dn").(string); dn != delegate {
+		t.Errorf("Expected to operate as %q, got %q", delegate, dn)
+	}
+}
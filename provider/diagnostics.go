@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+)
+
+// ldapResultHints holds an actionable summary and hint for the result codes
+// that are commonly returned when provisioning entries.
+var ldapResultHints = map[uint16]struct {
+	summary string
+	hint    string
+}{
+	ldap.LDAPResultInsufficientAccessRights: {
+		"Insufficient access rights",
+		"The bind user is not allowed to perform this operation; check the ACLs of the server for the entry and its attributes.",
+	},
+	ldap.LDAPResultConstraintViolation: {
+		"Constraint violation",
+		"A value violates a constraint of the server, e.g. a password policy, a size limit or a single-valued attribute holding more than one value.",
+	},
+	ldap.LDAPResultObjectClassViolation: {
+		"Object class violation",
+		"The entry does not conform to its object classes: a required attribute may be missing, an attribute may not be allowed by any of the classes, or the structural class may be missing or ambiguous.",
+	},
+	ldap.LDAPResultEntryAlreadyExists: {
+		"Entry already exists",
+		"An entry with this DN exists already; import it into the state or remove it from the server.",
+	},
+	ldap.LDAPResultNotAllowedOnNonLeaf: {
+		"Entry has children",
+		"The entry cannot be deleted or renamed while it has child entries; remove the children first.",
+	},
+	ldap.LDAPResultUnwillingToPerform: {
+		"Server is unwilling to perform the operation",
+		"The server refuses the operation, e.g. because the attribute is not modifiable, the password is not sent over a secure connection or the backend does not support it.",
+	},
+	ldap.LDAPResultNoSuchObject: {
+		"No such entry",
+		"The entry (or its parent, when adding) does not exist on the server.",
+	},
+	ldap.LDAPResultNoSuchAttribute: {
+		"No such attribute or value",
+		"The attribute or value to remove does not exist on the entry, e.g. because it was removed by other means.",
+	},
+	ldap.LDAPResultAttributeOrValueExists: {
+		"Attribute or value exists",
+		"The value to add is already present on the entry, e.g. because it was added by other means.",
+	},
+	ldap.LDAPResultUndefinedAttributeType: {
+		"Undefined attribute type",
+		"The attribute is not defined in the schema of the server; check its spelling.",
+	},
+	ldap.LDAPResultInvalidAttributeSyntax: {
+		"Invalid attribute syntax",
+		"A value does not conform to the syntax of its attribute.",
+	},
+	ldap.LDAPResultNamingViolation: {
+		"Naming violation",
+		"The RDN of the entry is not valid, e.g. its attribute is not allowed by the object classes or the parent does not allow this kind of child.",
+	},
+	ldap.LDAPResultAssertionFailed: {
+		"Entry changed since plan",
+		"The entry was changed by other means since the plan was made, so the values the update was based on no longer hold; refresh the state and plan again.",
+	},
+	ldap.LDAPResultAuthorizationDenied: {
+		"Proxied authorization denied",
+		"The bind user may not act as the proxied_authorization identity, or the identity does not exist; check the authzTo/authzFrom (or equivalent) settings of the server.",
+	},
+}
+
+// the result codes that are about specific attributes, whose diagnostics
+// point to the attributes of the resource
+var attributeResultCodes = map[uint16]bool{
+	ldap.LDAPResultConstraintViolation:       true,
+	ldap.LDAPResultObjectClassViolation:      true,
+	ldap.LDAPResultNoSuchAttribute:           true,
+	ldap.LDAPResultAttributeOrValueExists:    true,
+	ldap.LDAPResultUndefinedAttributeType:    true,
+	ldap.LDAPResultInvalidAttributeSyntax:    true,
+	ldap.LDAPResultInappropriateMatching:     true,
+	ldap.LDAPResultObjectClassModsProhibited: true,
+}
+
+// ldapDiagnostics translates an error returned for an operation on the given
+// DN into diagnostics: for common LDAP result codes the summary and detail
+// explain what went wrong, and for attribute related ones the diagnostic
+// points to the given attribute path (which may be nil). Other errors are
+// returned as they are.
+func ldapDiagnostics(err error, dn string, path cty.Path) diag.Diagnostics {
+	if err == nil {
+		return nil
+	}
+	ldapErr, ok := errors.Cause(err).(*ldap.Error)
+	if !ok {
+		return diag.FromErr(err)
+	}
+
+	summary := fmt.Sprintf("LDAP operation failed: %s", ldap.LDAPResultCodeMap[ldapErr.ResultCode])
+	hint := ""
+	if h, ok := ldapResultHints[ldapErr.ResultCode]; ok {
+		summary = h.summary
+		hint = h.hint
+	}
+
+	message := ""
+	if ldapErr.Err != nil {
+		message = ldapErr.Err.Error()
+	}
+
+	detail := []string{fmt.Sprintf("The server returned result code %d (%s) for %q.", ldapErr.ResultCode, ldap.LDAPResultCodeMap[ldapErr.ResultCode], dn)}
+	if dn == "" {
+		detail[0] = fmt.Sprintf("The server returned result code %d (%s).", ldapErr.ResultCode, ldap.LDAPResultCodeMap[ldapErr.ResultCode])
+	}
+	if message != "" {
+		detail = append(detail, fmt.Sprintf("Server message: %s", message))
+	}
+	if ad := describeADError(message); ad != "" {
+		detail = append(detail, ad)
+	}
+	if attribute := attributeFromMessage(message); attribute != "" {
+		detail = append(detail, fmt.Sprintf("Attribute: %s", attribute))
+	}
+	if ldapErr.MatchedDN != "" && ldapErr.ResultCode == ldap.LDAPResultNoSuchObject {
+		detail = append(detail, fmt.Sprintf("The closest existing entry is %q.", ldapErr.MatchedDN))
+	}
+	if hint != "" {
+		detail = append(detail, hint)
+	}
+
+	d := diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("%s on %q", summary, dn),
+		Detail:   strings.Join(detail, "\n\n"),
+	}
+	if attributeResultCodes[ldapErr.ResultCode] {
+		d.AttributePath = path
+	}
+	return diag.Diagnostics{d}
+}
+
+// some well-known Win32 error codes returned by Active Directory (and Samba)
+// at the beginning of their diagnostic messages
+var adErrorCodes = map[int64]string{
+	0x5:    "ERROR_ACCESS_DENIED",
+	0x52D:  "ERROR_PASSWORD_RESTRICTION",
+	0x52E:  "ERROR_LOGON_FAILURE",
+	0x530:  "ERROR_INVALID_LOGON_HOURS",
+	0x531:  "ERROR_INVALID_WORKSTATION",
+	0x532:  "ERROR_PASSWORD_EXPIRED",
+	0x533:  "ERROR_ACCOUNT_DISABLED",
+	0x701:  "ERROR_ACCOUNT_EXPIRED",
+	0x773:  "ERROR_PASSWORD_MUST_CHANGE",
+	0x775:  "ERROR_ACCOUNT_LOCKED_OUT",
+	0x200A: "ERROR_DS_NO_ATTRIBUTE_OR_VALUE",
+	0x200B: "ERROR_DS_INVALID_ATTRIBUTE_SYNTAX",
+	0x200C: "ERROR_DS_ATTRIBUTE_TYPE_UNDEFINED",
+	0x200D: "ERROR_DS_ATTRIBUTE_OR_VALUE_EXISTS",
+	0x2014: "ERROR_DS_OBJ_CLASS_VIOLATION",
+	0x2015: "ERROR_DS_CANT_ON_NON_LEAF",
+	0x2016: "ERROR_DS_CANT_ON_RDN",
+	0x2017: "ERROR_DS_CANT_MOD_OBJ_CLASS",
+	0x202F: "ERROR_DS_CONSTRAINT_VIOLATION",
+	0x2035: "ERROR_DS_UNWILLING_TO_PERFORM",
+	0x2071: "ERROR_DS_OBJ_STRING_NAME_EXISTS",
+	0x2077: "ERROR_DS_ILLEGAL_MOD_OPERATION",
+	0x208D: "ERROR_DS_OBJ_NOT_FOUND",
+	0x2098: "ERROR_DS_INSUFF_ACCESS_RIGHTS",
+}
+
+var adErrorRegexp = regexp.MustCompile(`^([0-9A-Fa-f]{8}): `)
+
+// describeADError decodes the hex error code Active Directory puts at the
+// beginning of its diagnostic messages, e.g. "0000052D: Constraint violation
+// - check_password_restrictions: ...".
+func describeADError(message string) string {
+	m := adErrorRegexp.FindStringSubmatch(message)
+	if m == nil {
+		return ""
+	}
+	code, err := strconv.ParseInt(m[1], 16, 64)
+	if err != nil {
+		return ""
+	}
+	name, ok := adErrorCodes[code]
+	if !ok {
+		name = "unknown Win32 error"
+	}
+	return fmt.Sprintf("Active Directory error code: 0x%s (%d, %s)", strings.ToUpper(m[1]), code, name)
+}
+
+var attributeMessageRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`attribute ['"]([\w;-]+)['"]`),
+	regexp.MustCompile(`^([\w;-]+): (?:value #\d+ )?(?:invalid|no user modification|multiple values|attribute type undefined)`),
+	regexp.MustCompile(`Att [0-9a-fA-F]+ \(([\w;-]+)\)`),
+}
+
+// attributeFromMessage extracts the name of the attribute involved from the
+// diagnostic message of the server, as far as it can be recognized.
+func attributeFromMessage(message string) string {
+	for _, r := range attributeMessageRegexps {
+		if m := r.FindStringSubmatch(message); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// dryRunDiagnostics fails an operation whose requests were only written to the
+// LDIF output, so that the state is left as it was.
+func dryRunDiagnostics(d *schema.ResourceData) diag.Diagnostics {
+	d.Partial(true)
+	if d.IsNewResource() {
+		d.SetId("")
+	}
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  "Dry run",
+		Detail:   "The changes were written to the LDIF output and not applied; the state is left as it was.",
+	}}
+}
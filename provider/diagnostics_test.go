@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/pkg/errors"
+)
+
+func TestLDAPDiagnostics(t *testing.T) {
+	err := errors.Wrapf(ldap.NewError(ldap.LDAPResultConstraintViolation, fmt.Errorf("0000052D: Constraint violation - check_password_restrictions: the password is too short")), "Modifying %q", "cn=jdoe,dc=example,dc=com")
+	diags := ldapDiagnostics(err, "cn=jdoe,dc=example,dc=com", cty.GetAttrPath("attributes"))
+	if len(diags) != 1 {
+		t.Fatalf("Expected one diagnostic, got %d", len(diags))
+	}
+	d := diags[0]
+	if d.Summary != `Constraint violation on "cn=jdoe,dc=example,dc=com"` {
+		t.Errorf("Unexpected summary %q", d.Summary)
+	}
+	if !strings.Contains(d.Detail, "ERROR_PASSWORD_RESTRICTION") {
+		t.Errorf("Expected the AD error code to be decoded, got %q", d.Detail)
+	}
+	if !d.AttributePath.Equals(cty.GetAttrPath("attributes")) {
+		t.Errorf("Expected the diagnostic to point to the attributes, got %v", d.AttributePath)
+	}
+
+	diags = ldapDiagnostics(ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("")), "cn=jdoe,ou=people,dc=example,dc=com", cty.GetAttrPath("attributes"))
+	if diags[0].AttributePath != nil {
+		t.Errorf("Expected no attribute path for result code 32, got %v", diags[0].AttributePath)
+	}
+
+	diags = ldapDiagnostics(fmt.Errorf("some error"), "cn=jdoe,dc=example,dc=com", nil)
+	if diags[0].Summary != "some error" {
+		t.Errorf("Expected other errors to be passed as they are, got %q", diags[0].Summary)
+	}
+}
+
+func TestAttributeFromMessage(t *testing.T) {
+	tests := map[string]string{
+		"mail: value #0 invalid per syntax": "mail",
+		"attribute 'foo' not allowed":       "foo",
+		"00000057: LdapErr: DSID-0C090D8A, comment: Error in attribute conversion operation, data 0, v4563":                                              "",
+		"000021C7: AtrErr: DSID-03200BBA, #1:\n\t0: 000021C7: DSID-03200BBA, problem 1005 (CONSTRAINT_ATT_TYPE), data 0, Att 90290 (userAccountControl)": "userAccountControl",
+	}
+	for message, expected := range tests {
+		if actual := attributeFromMessage(message); actual != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, message, actual)
+		}
+	}
+}
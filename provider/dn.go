@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// escapeDNValue escapes an attribute value so that it can be used in an RDN,
+// as described in RFC 4514, section 2.4.
+func escapeDNValue(value string) string {
+	var b strings.Builder
+	for i, c := range value {
+		switch {
+		case strings.ContainsRune(`"+,;<>\=`, c),
+			c == '#' && i == 0,
+			c == ' ' && (i == 0 || i == len(value)-1):
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		case c == 0:
+			b.WriteString(fmt.Sprintf("\\%02x", c))
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// baseDNPlaceholder stands for the base DN of the provider in DNs.
+const baseDNPlaceholder = "${base}"
+
+// the trees holding the configuration of the server, whose DNs are never
+// relative to the base DN of the provider
+var configurationDNs = []string{"cn=config", "cn=schema", "cn=subschema", "cn=monitor"}
+
+// expandDN turns a DN relative to baseDN into an absolute one: the "${base}"
+// placeholder is replaced by baseDN, and baseDN is appended to DNs that are
+// located neither under it, nor under one of the suffixes of the directory,
+// nor in the configuration of the server.
+func expandDN(dn, baseDN string, suffixes []string) string {
+	if baseDN == "" {
+		return dn
+	}
+	if strings.Contains(dn, baseDNPlaceholder) {
+		return strings.ReplaceAll(dn, baseDNPlaceholder, baseDN)
+	}
+	if dn == "" {
+		return baseDN
+	}
+	if isDescendantOrSelf(dn, baseDN) {
+		return dn
+	}
+	for _, suffix := range append(suffixes, configurationDNs...) {
+		if isDescendantOrSelf(dn, suffix) {
+			return dn
+		}
+	}
+	return dn + "," + baseDN
+}
+
+// relativeDN strips baseDN from a DN located under it.
+func relativeDN(dn, baseDN string) string {
+	if baseDN == "" || !isDescendantOrSelf(dn, baseDN) || dnDepth(dn) == dnDepth(baseDN) {
+		return dn
+	}
+	rdns := []string{}
+	for rest := dn; dnDepth(rest) > dnDepth(baseDN); {
+		var rdn string
+		rdn, rest = splitDN(rest)
+		rdns = append(rdns, rdn)
+	}
+	return strings.Join(rdns, ",")
+}
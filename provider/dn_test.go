@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"testing"
+)
+
+func TestExpandDN(t *testing.T) {
+	testCases := []struct {
+		dn, baseDN, expanded, relative string
+	}{
+		{"cn=jdoe,ou=people", "dc=example,dc=com", "cn=jdoe,ou=people,dc=example,dc=com", "cn=jdoe,ou=people"},
+		{"cn=jdoe,ou=people,${base}", "dc=example,dc=com", "cn=jdoe,ou=people,dc=example,dc=com", "cn=jdoe,ou=people"},
+		{"cn=jdoe,dc=example,dc=com", "dc=example,dc=com", "cn=jdoe,dc=example,dc=com", "cn=jdoe"},
+		{"cn=jdoe,DC=example, DC=com", "dc=example,dc=com", "cn=jdoe,DC=example, DC=com", "cn=jdoe"},
+		{"dc=example,dc=com", "dc=example,dc=com", "dc=example,dc=com", "dc=example,dc=com"},
+		{"olcDatabase={1}mdb,cn=config", "dc=example,dc=com", "olcDatabase={1}mdb,cn=config", "olcDatabase={1}mdb,cn=config"},
+		{"cn=jdoe,ou=people", "", "cn=jdoe,ou=people", "cn=jdoe,ou=people"},
+		// DNs under other suffixes of the directory are absolute
+		{"cn=jdoe,ou=people,o=other", "dc=example,dc=com", "cn=jdoe,ou=people,o=other", "cn=jdoe,ou=people,o=other"},
+	}
+	suffixes := []string{"dc=example,dc=com", "o=other"}
+	for _, tc := range testCases {
+		expanded := expandDN(tc.dn, tc.baseDN, suffixes)
+		if expanded != tc.expanded {
+			t.Errorf("Expected %q under %q to expand to %q, got %q", tc.dn, tc.baseDN, tc.expanded, expanded)
+		}
+		if relative := relativeDN(expanded, tc.baseDN); relative != tc.relative {
+			t.Errorf("Expected %q to be %q relative to %q, got %q", expanded, tc.relative, tc.baseDN, relative)
+		}
+	}
+}
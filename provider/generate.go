@@ -0,0 +1,323 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// GenerateConfig holds the settings of the generation of Terraform
+// configuration from an existing directory subtree.
+type GenerateConfig struct {
+	// BaseDN is the DN of the root of the subtree
+	BaseDN string
+	// Scope is one of ldap.ScopeBaseObject, ldap.ScopeSingleLevel or
+	// ldap.ScopeWholeSubtree
+	Scope int
+	// Filter selects the entries to generate resources for
+	Filter string
+	// SkipAttributes lists further attributes to leave out
+	SkipAttributes []string
+}
+
+// Generate searches the subtree described by the configuration and writes
+// an ldap_object resource and an import block for each entry found.
+func Generate(ctx context.Context, conn *ldap.Conn, config GenerateConfig, w io.Writer) error {
+	filter := config.Filter
+	if filter == "" {
+		filter = "(objectClass=*)"
+	}
+	request := ldap.NewSearchRequest(
+		config.BaseDN,
+		config.Scope,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		filter,
+		[]string{"*"},
+		nil,
+	)
+	sr, err := NewClient(conn).Search(ctx, request)
+	if err != nil {
+		return err
+	}
+	debugLog("generate - found %d entries under %q", len(sr.Entries), config.BaseDN)
+	_, err = io.WriteString(w, renderGeneratedConfig(sr.Entries, config.SkipAttributes))
+	return err
+}
+
+// renderGeneratedConfig renders the ldap_object resources and import blocks
+// for the entries: parents come first and DNs of other generated entries are
+// turned into references to them.
+func renderGeneratedConfig(entries []*ldap.Entry, skipAttributes []string) string {
+	sorted := make([]*ldap.Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		di, dj := dnDepth(sorted[i].DN), dnDepth(sorted[j].DN)
+		if di == dj {
+			return strings.ToLower(sorted[i].DN) < strings.ToLower(sorted[j].DN)
+		}
+		return di < dj
+	})
+
+	names := map[string]bool{}
+	resources := map[string]string{}
+	for _, entry := range sorted {
+		resources[strings.ToLower(entry.DN)] = generatedResourceName(entry.DN, names)
+	}
+	references := generatedReferences(sorted, resources)
+
+	var b strings.Builder
+	for _, entry := range sorted {
+		key := strings.ToLower(entry.DN)
+		name := resources[key]
+
+		// attributes which are not rendered are skipped by the resource, so
+		// that importing it plans no changes
+		attributes := []string{}
+		binary := []string{}
+		secret := []string{}
+		skipped := []string{}
+		objectClasses := entry.GetAttributeValues("objectClass")
+		for _, attribute := range entry.Attributes {
+			if strings.EqualFold(attribute.Name, "objectClass") ||
+				isServerManagedAttribute(attribute.Name, objectClasses) {
+				continue
+			}
+			if containsEqualFold(skipAttributes, attribute.Name) {
+				skipped = append(skipped, attribute.Name)
+				continue
+			}
+			// passwords and their hashes don't belong in configuration
+			if containsEqualFold(ldifRedactedAttributes, attribute.Name) {
+				secret = append(secret, attribute.Name)
+				skipped = append(skipped, attribute.Name)
+				continue
+			}
+			if !validUTF8Values(attribute.Values) {
+				binary = append(binary, attribute.Name)
+				skipped = append(skipped, attribute.Name)
+				continue
+			}
+			for _, value := range attribute.Values {
+				if isRDNValue(entry.DN, attribute.Name, value) {
+					continue
+				}
+				v := hclString(value)
+				if references[key][strings.ToLower(value)] {
+					v = fmt.Sprintf("ldap_object.%s.dn", resources[strings.ToLower(value)])
+				}
+				attributes = append(attributes, fmt.Sprintf("    { %s = %s },\n", hclAttributeName(attribute.Name), v))
+			}
+		}
+
+		// the arguments are aligned like terraform fmt does
+		width := len("object_classes")
+		if len(skipped) > 0 {
+			width = len("skip_attributes")
+		}
+		fmt.Fprintf(&b, "resource \"ldap_object\" %q {\n", name)
+		fmt.Fprintf(&b, "  %-*s = %s\n", width, "dn", generatedDN(entry.DN, resources))
+
+		classes := []string{}
+		for _, oc := range entry.GetAttributeValues("objectClass") {
+			classes = append(classes, hclString(oc))
+		}
+		fmt.Fprintf(&b, "  %-*s = [%s]\n", width, "object_classes", strings.Join(classes, ", "))
+
+		id := entry.DN
+		if len(skipped) > 0 {
+			names := []string{}
+			for _, name := range skipped {
+				names = append(names, hclString(name))
+			}
+			fmt.Fprintf(&b, "  skip_attributes = [%s]\n", strings.Join(names, ", "))
+			id += "|skip=" + strings.Join(skipped, ",")
+		}
+		for _, name := range binary {
+			fmt.Fprintf(&b, "  # the binary attribute %s has been left out\n", name)
+		}
+		for _, name := range secret {
+			fmt.Fprintf(&b, "  # the secret attribute %s has been left out\n", name)
+		}
+		if len(attributes) > 0 {
+			b.WriteString("  attributes = [\n")
+			for _, a := range attributes {
+				b.WriteString(a)
+			}
+			b.WriteString("  ]\n")
+		}
+		b.WriteString("}\n\n")
+
+		b.WriteString("import {\n")
+		fmt.Fprintf(&b, "  to = ldap_object.%s\n", name)
+		fmt.Fprintf(&b, "  id = %s\n", hclString(id))
+		b.WriteString("}\n\n")
+	}
+	return b.String()
+}
+
+// generatedReferences determines which attribute values of the entries are
+// rendered as references to other generated entries: a reference is left out
+// if it would form a cycle together with the parents and the references
+// accepted before.
+func generatedReferences(entries []*ldap.Entry, resources map[string]string) map[string]map[string]bool {
+	dependencies := map[string]map[string]bool{}
+	for _, entry := range entries {
+		key := strings.ToLower(entry.DN)
+		dependencies[key] = map[string]bool{}
+		if _, parent := splitDN(entry.DN); parent != "" {
+			if _, ok := resources[strings.ToLower(parent)]; ok {
+				dependencies[key][strings.ToLower(parent)] = true
+			}
+		}
+	}
+
+	var reaches func(from, to string, visited map[string]bool) bool
+	reaches = func(from, to string, visited map[string]bool) bool {
+		if from == to {
+			return true
+		}
+		visited[from] = true
+		for next := range dependencies[from] {
+			if !visited[next] && reaches(next, to, visited) {
+				return true
+			}
+		}
+		return false
+	}
+
+	references := map[string]map[string]bool{}
+	for _, entry := range entries {
+		key := strings.ToLower(entry.DN)
+		references[key] = map[string]bool{}
+		for _, attribute := range entry.Attributes {
+			for _, value := range attribute.Values {
+				target := strings.ToLower(value)
+				if _, ok := resources[target]; !ok || reaches(target, key, map[string]bool{}) {
+					continue
+				}
+				references[key][target] = true
+				dependencies[key][target] = true
+			}
+		}
+	}
+	return references
+}
+
+// generatedDN renders the DN of an entry, referencing the DN of its parent if
+// it is generated as well.
+func generatedDN(dn string, rendered map[string]string) string {
+	rdn, parent := splitDN(dn)
+	if parent != "" {
+		if ref, ok := rendered[strings.ToLower(parent)]; ok {
+			return fmt.Sprintf("\"%s,${ldap_object.%s.dn}\"", hclEscape(rdn), ref)
+		}
+	}
+	return hclString(dn)
+}
+
+// validUTF8Values checks whether the values are text rather than binary data.
+func validUTF8Values(values []string) bool {
+	for _, value := range values {
+		if !utf8.ValidString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitDN splits a DN into its first RDN and the DN of its parent at the
+// first unescaped comma.
+func splitDN(dn string) (string, string) {
+	for i := 0; i < len(dn); i++ {
+		switch dn[i] {
+		case '\\':
+			i++
+		case ',':
+			return dn[:i], strings.TrimLeft(dn[i+1:], " ")
+		}
+	}
+	return dn, ""
+}
+
+// generatedResourceName derives a unique resource name from the value of the
+// RDN of the entry.
+func generatedResourceName(dn string, names map[string]bool) string {
+	rdn, _ := splitDN(dn)
+	if i := strings.Index(rdn, "="); i >= 0 {
+		rdn = rdn[i+1:]
+	}
+	var b strings.Builder
+	for _, c := range strings.ToLower(rdn) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '-' {
+			b.WriteRune(c)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+			b.WriteRune('_')
+		}
+	}
+	base := strings.Trim(b.String(), "_")
+	if base == "" || (base[0] >= '0' && base[0] <= '9') || base[0] == '-' {
+		base = "entry_" + base
+	}
+	name := base
+	for i := 2; names[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	names[name] = true
+	return name
+}
+
+// hclAttributeName renders an attribute name as an object key, quoting it
+// when it isn't a valid identifier (e.g. because of options like ";binary").
+func hclAttributeName(name string) string {
+	for i, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || (i > 0 && ((c >= '0' && c <= '9') || c == '-'))) {
+			return hclString(name)
+		}
+	}
+	return name
+}
+
+// hclString renders a quoted HCL string literal.
+func hclString(s string) string {
+	return "\"" + hclEscape(s) + "\""
+}
+
+// hclEscape escapes a string to be used inside an HCL string literal,
+// including the template sequences.
+func hclEscape(s string) string {
+	var b strings.Builder
+	for i, c := range s {
+		switch c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '$', '%':
+			b.WriteRune(c)
+			if i+1 < len(s) && s[i+1] == '{' {
+				b.WriteRune(c)
+			}
+		default:
+			if c < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, c)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+	}
+	return b.String()
+}
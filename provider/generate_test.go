@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRenderGeneratedConfig(t *testing.T) {
+	entries := []*ldap.Entry{
+		ldap.NewEntry("cn=admins,ou=groups,dc=example,dc=com", map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"cn":          {"admins"},
+			"member":      {"cn=John Doe,ou=people,dc=example,dc=com", "cn=root,dc=example,dc=com"},
+		}),
+		ldap.NewEntry("cn=John Doe,ou=people,dc=example,dc=com", map[string][]string{
+			"objectClass":  {"inetOrgPerson"},
+			"cn":           {"John Doe"},
+			"sn":           {"Doe"},
+			"description":  {"costs ${price}"},
+			"whenCreated":  {"20210101000000.0Z"},
+			"jpegPhoto":    {"\xff\xd8\xff"},
+			"userPassword": {"{SSHA}c2VjcmV0"},
+		}),
+		ldap.NewEntry("ou=people,dc=example,dc=com", map[string][]string{
+			"objectClass": {"organizationalUnit"},
+			"ou":          {"people"},
+		}),
+		ldap.NewEntry("ou=groups,dc=example,dc=com", map[string][]string{
+			"objectClass": {"organizationalUnit"},
+			"ou":          {"groups"},
+		}),
+	}
+	config := renderGeneratedConfig(entries, []string{"sn"})
+
+	expected := []string{
+		`resource "ldap_object" "groups" {
+  dn             = "ou=groups,dc=example,dc=com"
+  object_classes = ["organizationalUnit"]
+}
+
+import {
+  to = ldap_object.groups
+  id = "ou=groups,dc=example,dc=com"
+}
+`,
+		`resource "ldap_object" "john_doe" {
+  dn              = "cn=John Doe,${ldap_object.people.dn}"
+  object_classes  = ["inetOrgPerson"]
+  skip_attributes = ["jpegPhoto", "sn", "userPassword"]
+  # the binary attribute jpegPhoto has been left out
+  # the secret attribute userPassword has been left out
+  attributes = [
+    { description = "costs $${price}" },
+  ]
+}
+
+import {
+  to = ldap_object.john_doe
+  id = "cn=John Doe,ou=people,dc=example,dc=com|skip=jpegPhoto,sn,userPassword"
+}
+`,
+		`    { member = ldap_object.john_doe.dn },
+    { member = "cn=root,dc=example,dc=com" },
+`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(config, e) {
+			t.Errorf("Expected the configuration to contain\n%s\ngot\n%s", e, config)
+		}
+	}
+	if strings.Index(config, `"people"`) > strings.Index(config, `"john_doe"`) {
+		t.Errorf("Expected parents to come first, got\n%s", config)
+	}
+	if strings.Contains(config, "whenCreated") || strings.Contains(config, "{SSHA}") {
+		t.Errorf("Expected server-managed attributes and passwords to be left out, got\n%s", config)
+	}
+}
+
+func TestGeneratedResourceName(t *testing.T) {
+	names := map[string]bool{}
+	tests := []struct {
+		dn       string
+		expected string
+	}{
+		{"cn=John Doe,dc=example,dc=com", "john_doe"},
+		{"cn=john.doe,dc=example,dc=com", "john_doe_2"},
+		{"uid=42,dc=example,dc=com", "entry_42"},
+		{"cn=Müller\\, Hans,dc=example,dc=com", "m_ller_hans"},
+	}
+	for _, test := range tests {
+		if actual := generatedResourceName(test.dn, names); actual != test.expected {
+			t.Errorf("Expected %q for %q, got %q", test.expected, test.dn, actual)
+		}
+	}
+}
+
+func TestGeneratedReferencesAvoidCycles(t *testing.T) {
+	entries := []*ldap.Entry{
+		ldap.NewEntry("cn=a,dc=example,dc=com", map[string][]string{"seeAlso": {"cn=b,dc=example,dc=com"}}),
+		ldap.NewEntry("cn=b,dc=example,dc=com", map[string][]string{"seeAlso": {"cn=a,dc=example,dc=com"}}),
+	}
+	resources := map[string]string{
+		"cn=a,dc=example,dc=com": "a",
+		"cn=b,dc=example,dc=com": "b",
+	}
+	references := generatedReferences(entries, resources)
+	if !references["cn=a,dc=example,dc=com"]["cn=b,dc=example,dc=com"] {
+		t.Errorf("Expected a to reference b")
+	}
+	if references["cn=b,dc=example,dc=com"]["cn=a,dc=example,dc=com"] {
+		t.Errorf("Expected b not to reference a, as it would form a cycle")
+	}
+}
+
+func TestGeneratedConfigImportsWithoutChanges(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	people, groups := "ou=people,"+testBaseDN, "ou=groups,"+testBaseDN
+	jdoe := "cn=John Doe," + people
+	s.PutEntry(people, map[string][]string{"objectClass": {"organizationalUnit"}, "ou": {"people"}})
+	s.PutEntry(groups, map[string][]string{"objectClass": {"organizationalUnit"}, "ou": {"groups"}})
+	s.PutEntry(jdoe, map[string][]string{
+		"objectClass": {"inetOrgPerson"},
+		"cn":          {"John Doe"},
+		"sn":          {"Doe"},
+		"description": {"costs ${price}"},
+		"jpegPhoto":   {"\xff\xd8\xff"},
+	})
+	s.PutEntry("cn=admins,"+groups, map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {"admins"},
+		"member":      {jdoe},
+	})
+	meta := testProviderMeta(t, s)
+
+	var generated strings.Builder
+	config := GenerateConfig{BaseDN: testBaseDN, Scope: ldap.ScopeWholeSubtree, SkipAttributes: []string{"sn"}}
+	if err := Generate(context.Background(), meta.(*Client).conn, config, &generated); err != nil {
+		t.Fatalf("Unable to generate the configuration: %v", err)
+	}
+	file, diags := hclsyntax.ParseConfig([]byte(generated.String()), "generated.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("Unable to parse the configuration: %v\n%s", diags, generated.String())
+	}
+
+	// the DNs reference parents only, which come first, while the attributes
+	// may reference any other resource
+	blocks := file.Body.(*hclsyntax.Body).Blocks
+	resources := map[string]cty.Value{}
+	evaluate := func(expression hcl.Expression) cty.Value {
+		value, diags := expression.Value(&hcl.EvalContext{
+			Variables: map[string]cty.Value{"ldap_object": cty.ObjectVal(resources)},
+		})
+		if diags.HasErrors() {
+			t.Fatalf("Unable to evaluate %v: %v", expression.Range(), diags)
+		}
+		return value
+	}
+	for _, block := range blocks {
+		if block.Type == "resource" {
+			resources[block.Labels[1]] = cty.ObjectVal(map[string]cty.Value{"dn": evaluate(block.Body.Attributes["dn"].Expr)})
+		}
+	}
+
+	r := resourceLDAPObject()
+	var raw map[string]interface{}
+	for _, block := range blocks {
+		switch block.Type {
+		case "resource":
+			raw = map[string]interface{}{}
+			for name, attribute := range block.Body.Attributes {
+				raw[name] = testCtyToRaw(evaluate(attribute.Expr))
+			}
+		case "import":
+			id := evaluate(block.Body.Attributes["id"].Expr).AsString()
+			state := testImport(t, r, id, meta)
+			if !testPlanIsEmpty(t, r, state, raw, meta) {
+				t.Errorf("Expected no changes after importing %q with\n%v", id, raw)
+			}
+		}
+	}
+}
+
+// testCtyToRaw turns a value into the form of a raw configuration.
+func testCtyToRaw(value cty.Value) interface{} {
+	switch {
+	case value.Type() == cty.String:
+		return value.AsString()
+	case value.Type().IsObjectType() || value.Type().IsMapType():
+		m := map[string]interface{}{}
+		for k, v := range value.AsValueMap() {
+			m[k] = testCtyToRaw(v)
+		}
+		return m
+	default:
+		l := []interface{}{}
+		for _, v := range value.AsValueSlice() {
+			l = append(l, testCtyToRaw(v))
+		}
+		return l
+	}
+}
@@ -0,0 +1,1068 @@
+package provider
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// testLDAPServer is a minimal in-memory LDAP server the tests run against.
+// It implements the operations the provider uses with simplified semantics:
+// values are compared case-insensitively, there is no schema checking beyond
+// requiring object classes, and naming attributes are added automatically,
+// like OpenLDAP and Active Directory do. Added entries get the operational
+// attributes in testOperationalAttributes, which are only returned when
+// asked for by name or with "+".
+type testLDAPServer struct {
+	URL          string
+	BindDN       string
+	BindPassword string
+
+	listener net.Listener
+	mutex    sync.Mutex
+	entries  map[string]*ldap.Entry
+	rootDSE  *ldap.Entry
+	sequence int
+
+	// the critical controls that are supported
+	controls []string
+	// the requests of the pending transactions by identifier
+	transactions map[string][]func() testLDAPResult
+
+	// searches are answered after the delay
+	delay time.Duration
+	// the time limit of the last search
+	timeLimit int64
+}
+
+// the OID of the Who am I? operation (RFC 4532)
+const whoAmIOID = "1.3.6.1.4.1.4203.1.11.3"
+
+// the operational attributes maintained by the server
+var testOperationalAttributes = []string{"entryUUID", "createTimestamp", "modifyTimestamp", "creatorsName", "modifiersName"}
+
+// newTestLDAPServer starts a server holding the base entry and stops it when
+// the test is done.
+func newTestLDAPServer(t *testing.T, baseDN string) *testLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to start LDAP server: %v", err)
+	}
+	s := &testLDAPServer{
+		URL:          fmt.Sprintf("ldap://%s", listener.Addr().String()),
+		BindDN:       "cn=admin," + baseDN,
+		BindPassword: "secret",
+		listener:     listener,
+		entries:      map[string]*ldap.Entry{},
+		transactions: map[string][]func() testLDAPResult{},
+		controls:     []string{assertionControlOID, permissiveModifyControlOID, relaxRulesControlOID, proxiedAuthorizationControlOID},
+		rootDSE: ldap.NewEntry("", map[string][]string{
+			"objectClass":          {"top"},
+			"supportedExtension":   {whoAmIOID},
+			"supportedControl":     {assertionControlOID, permissiveModifyControlOID, relaxRulesControlOID, proxiedAuthorizationControlOID},
+			"namingContexts":       {baseDN},
+			"supportedLDAPVersion": {"3"},
+		}),
+	}
+	rdn, _ := splitDN(baseDN)
+	kv := strings.SplitN(rdn, "=", 2)
+	s.PutEntry(baseDN, map[string][]string{
+		"objectClass": {"top", "domain"},
+		kv[0]:         {kv[1]},
+	})
+
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return s
+}
+
+// PutEntry creates or overwrites an entry, bypassing all checks.
+func (s *testLDAPServer) PutEntry(dn string, attributes map[string][]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, _ := testDNKeys(dn)
+	s.entries[key] = ldap.NewEntry(dn, attributes)
+}
+
+// SetAttribute replaces (or, without values, removes) an attribute of an
+// entry behind the back of the provider.
+func (s *testLDAPServer) SetAttribute(dn, name string, values ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, _ := testDNKeys(dn)
+	if entry, ok := s.entries[key]; ok {
+		testSetValues(entry, name, values)
+	}
+}
+
+// SetObjectClasses publishes the given object class definitions in a
+// subschema entry advertised by the root DSE.
+func (s *testLDAPServer) SetObjectClasses(definitions ...string) {
+	s.PutEntry("cn=Subschema", map[string][]string{
+		"objectClass":   {"top", "subschema"},
+		"cn":            {"Subschema"},
+		"objectClasses": definitions,
+	})
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	testSetValues(s.rootDSE, "subschemaSubentry", []string{"cn=Subschema"})
+}
+
+// EnableTransactions advertises and implements transactions (RFC 5805).
+func (s *testLDAPServer) EnableTransactions() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.controls = append(s.controls, transactionControlOID)
+	testSetValues(s.rootDSE, "supportedExtension", append(s.rootDSE.GetAttributeValues("supportedExtension"), startTransactionOID, endTransactionOID))
+	testSetValues(s.rootDSE, "supportedControl", append(s.rootDSE.GetAttributeValues("supportedControl"), transactionControlOID))
+}
+
+// DelaySearches makes the server wait before answering searches.
+func (s *testLDAPServer) DelaySearches(delay time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.delay = delay
+}
+
+// TimeLimit returns the time limit of the last search.
+func (s *testLDAPServer) TimeLimit() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.timeLimit
+}
+
+// DeleteEntry removes an entry behind the back of the provider.
+func (s *testLDAPServer) DeleteEntry(dn string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, _ := testDNKeys(dn)
+	delete(s.entries, key)
+}
+
+// Entry returns a copy of an entry, or nil if it doesn't exist.
+func (s *testLDAPServer) Entry(dn string) *ldap.Entry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, _ := testDNKeys(dn)
+	if entry, ok := s.entries[key]; ok {
+		return testCopyEntry(entry)
+	}
+	return nil
+}
+
+// Values returns the values of an attribute of an entry.
+func (s *testLDAPServer) Values(dn, name string) []string {
+	entry := s.Entry(dn)
+	if entry == nil {
+		return nil
+	}
+	return entry.GetEqualFoldAttributeValues(name)
+}
+
+func (s *testLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// testLDAPConn is a client connection, along with the DN it is bound as.
+type testLDAPConn struct {
+	net.Conn
+	bound string
+}
+
+func (c *testLDAPConn) send(id int64, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	packet.AppendChild(op)
+	c.Write(packet.Bytes())
+}
+
+// testLDAPResult is the outcome of an operation.
+type testLDAPResult struct {
+	code    uint16
+	matched string
+	message string
+}
+
+func testResult(code uint16, format string, v ...interface{}) testLDAPResult {
+	return testLDAPResult{code: code, message: fmt.Sprintf(format, v...)}
+}
+
+var testSuccess = testLDAPResult{code: ldap.LDAPResultSuccess}
+
+func (r testLDAPResult) packet(tag int) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ber.Tag(tag), nil, "Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(r.code), "resultCode"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, r.matched, "matchedDN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, r.message, "diagnosticMessage"))
+	return p
+}
+
+func (s *testLDAPServer) handle(netConn net.Conn) {
+	defer netConn.Close()
+	conn := &testLDAPConn{Conn: netConn}
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		var controls []*ber.Packet
+		if len(packet.Children) > 2 {
+			controls = packet.Children[2].Children
+		}
+
+		if op.Tag == ldap.ApplicationUnbindRequest {
+			return
+		}
+		if op.Tag == ldap.ApplicationAbandonRequest {
+			continue
+		}
+		if result, ok := s.checkControls(controls); !ok {
+			conn.send(id, result.packet(testResponseTags[int(op.Tag)]))
+			continue
+		}
+		identity, result, ok := s.authorize(conn, controls)
+		if !ok {
+			conn.send(id, result.packet(testResponseTags[int(op.Tag)]))
+			continue
+		}
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			conn.send(id, s.bind(conn, op).packet(ldap.ApplicationBindResponse))
+		case ldap.ApplicationSearchRequest:
+			s.mutex.Lock()
+			s.timeLimit, _ = op.Children[4].Value.(int64)
+			delay := s.delay
+			s.mutex.Unlock()
+			time.Sleep(delay)
+			s.search(conn, id, op)
+		case ldap.ApplicationAddRequest:
+			conn.send(id, s.write(identity, controls, func() testLDAPResult { return s.add(op, controls, identity) }).packet(ldap.ApplicationAddResponse))
+		case ldap.ApplicationModifyRequest:
+			conn.send(id, s.write(identity, controls, func() testLDAPResult {
+				if result, ok := s.checkAssertion(controls, testString(op.Children[0])); !ok {
+					return result
+				}
+				return s.modify(op, controls, identity)
+			}).packet(ldap.ApplicationModifyResponse))
+		case ldap.ApplicationDelRequest:
+			conn.send(id, s.write(identity, controls, func() testLDAPResult { return s.del(op) }).packet(ldap.ApplicationDelResponse))
+		case ldap.ApplicationModifyDNRequest:
+			conn.send(id, s.write(identity, controls, func() testLDAPResult { return s.modifyDN(op) }).packet(ldap.ApplicationModifyDNResponse))
+		case ldap.ApplicationCompareRequest:
+			conn.send(id, s.compare(op).packet(ldap.ApplicationCompareResponse))
+		case ldap.ApplicationExtendedRequest:
+			conn.send(id, s.extended(identity, op))
+		default:
+			return
+		}
+	}
+}
+
+// the response tags of the requests
+var testResponseTags = map[int]int{
+	ldap.ApplicationBindRequest:     ldap.ApplicationBindResponse,
+	ldap.ApplicationSearchRequest:   ldap.ApplicationSearchResultDone,
+	ldap.ApplicationModifyRequest:   ldap.ApplicationModifyResponse,
+	ldap.ApplicationAddRequest:      ldap.ApplicationAddResponse,
+	ldap.ApplicationDelRequest:      ldap.ApplicationDelResponse,
+	ldap.ApplicationModifyDNRequest: ldap.ApplicationModifyDNResponse,
+	ldap.ApplicationCompareRequest:  ldap.ApplicationCompareResponse,
+	ldap.ApplicationExtendedRequest: ldap.ApplicationExtendedResponse,
+}
+
+// checkControls rejects requests with critical controls that are not
+// supported.
+func (s *testLDAPServer) checkControls(controls []*ber.Packet) (testLDAPResult, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, control := range controls {
+		if len(control.Children) < 2 || testContains(s.controls, testString(control.Children[0])) {
+			continue
+		}
+		if critical, ok := control.Children[1].Value.(bool); ok && critical {
+			return testResult(ldap.LDAPResultUnavailableCriticalExtension, "critical control %s is not supported", testString(control.Children[0])), false
+		}
+	}
+	return testSuccess, true
+}
+
+// checkAssertion evaluates the assertion control, if any, against the entry.
+func (s *testLDAPServer) checkAssertion(controls []*ber.Packet, dn string) (testLDAPResult, bool) {
+	value, ok := testControlValue(controls, assertionControlOID)
+	if !ok {
+		return testSuccess, true
+	}
+	filter, err := ber.DecodePacketErr([]byte(value))
+	if err != nil {
+		return testResult(ldap.LDAPResultProtocolError, "invalid assertion: %v", err), false
+	}
+	key, _ := testDNKeys(dn)
+	if entry, ok := s.entries[key]; ok && !testMatchFilter(entry, filter) {
+		return testResult(ldap.LDAPResultAssertionFailed, "assertion failed"), false
+	}
+	return testSuccess, true
+}
+
+// testControlValue returns the value of a control, if it is present.
+func testControlValue(controls []*ber.Packet, oid string) (string, bool) {
+	for _, control := range controls {
+		if testString(control.Children[0]) != oid {
+			continue
+		}
+		if last := control.Children[len(control.Children)-1]; len(control.Children) > 1 && last.Tag == ber.TagOctetString {
+			return testString(last), true
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// authorize returns the identity a request is performed as: the one the
+// connection is bound as or, if the admin sends the Proxied Authorization
+// control, the one of the control.
+func (s *testLDAPServer) authorize(conn *testLDAPConn, controls []*ber.Packet) (string, testLDAPResult, bool) {
+	authzID, ok := testControlValue(controls, proxiedAuthorizationControlOID)
+	if !ok {
+		return conn.bound, testSuccess, true
+	}
+	if !strings.EqualFold(conn.bound, s.BindDN) {
+		return "", testResult(ldap.LDAPResultAuthorizationDenied, "%q may not proxy", conn.bound), false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch {
+	case authzID == "":
+		return "", testSuccess, true
+	case strings.HasPrefix(authzID, "dn:"):
+		key, _ := testDNKeys(strings.TrimPrefix(authzID, "dn:"))
+		if entry, ok := s.entries[key]; ok {
+			return entry.DN, testSuccess, true
+		}
+	case strings.HasPrefix(authzID, "u:"):
+		for _, entry := range s.entries {
+			if testContains(entry.GetEqualFoldAttributeValues("uid"), strings.TrimPrefix(authzID, "u:")) {
+				return entry.DN, testSuccess, true
+			}
+		}
+	}
+	return "", testResult(ldap.LDAPResultAuthorizationDenied, "unknown authorization identity %q", authzID), false
+}
+
+// write runs a write operation if the identity is not anonymous, or queues it
+// if it is part of a transaction.
+func (s *testLDAPServer) write(identity string, controls []*ber.Packet, op func() testLDAPResult) testLDAPResult {
+	if identity == "" {
+		return testResult(ldap.LDAPResultInsufficientAccessRights, "anonymous writes are not allowed")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if id, ok := testControlValue(controls, transactionControlOID); ok {
+		if _, ok := s.transactions[id]; !ok {
+			return testResult(ldap.LDAPResultUnwillingToPerform, "unknown transaction")
+		}
+		s.transactions[id] = append(s.transactions[id], op)
+		return testSuccess
+	}
+	return op()
+}
+
+// extended implements the extended operations.
+func (s *testLDAPServer) extended(identity string, op *ber.Packet) *ber.Packet {
+	name := testString(op.Children[0])
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !testContains(s.rootDSE.GetAttributeValues("supportedExtension"), name) {
+		return testResult(ldap.LDAPResultProtocolError, "unsupported extended operation").packet(ldap.ApplicationExtendedResponse)
+	}
+
+	switch name {
+	case whoAmIOID:
+		p := testSuccess.packet(ldap.ApplicationExtendedResponse)
+		if identity != "" {
+			p.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 11, "dn:"+identity, "responseValue"))
+		}
+		return p
+	case startTransactionOID:
+		s.sequence++
+		id := fmt.Sprintf("txn%d", s.sequence)
+		s.transactions[id] = []func() testLDAPResult{}
+		// the response has a value but no name
+		p := testSuccess.packet(ldap.ApplicationExtendedResponse)
+		p.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 11, id, "responseValue"))
+		return p
+	case endTransactionOID:
+		commit, id := true, ""
+		if len(op.Children) > 1 {
+			value, err := ber.DecodePacketErr(op.Children[1].Data.Bytes())
+			if err != nil {
+				return testResult(ldap.LDAPResultProtocolError, "invalid request value").packet(ldap.ApplicationExtendedResponse)
+			}
+			for _, child := range value.Children {
+				if c, ok := child.Value.(bool); ok {
+					commit = c
+				} else {
+					id = testString(child)
+				}
+			}
+		}
+		ops, ok := s.transactions[id]
+		if !ok {
+			return testResult(ldap.LDAPResultUnwillingToPerform, "unknown transaction").packet(ldap.ApplicationExtendedResponse)
+		}
+		delete(s.transactions, id)
+		if !commit {
+			return testSuccess.packet(ldap.ApplicationExtendedResponse)
+		}
+		// all or nothing
+		saved := map[string]*ldap.Entry{}
+		for key, entry := range s.entries {
+			saved[key] = testCopyEntry(entry)
+		}
+		for _, op := range ops {
+			if result := op(); result.code != ldap.LDAPResultSuccess {
+				s.entries = saved
+				return result.packet(ldap.ApplicationExtendedResponse)
+			}
+		}
+		return testSuccess.packet(ldap.ApplicationExtendedResponse)
+	}
+	return testResult(ldap.LDAPResultProtocolError, "unsupported extended operation").packet(ldap.ApplicationExtendedResponse)
+}
+
+func (s *testLDAPServer) bind(conn *testLDAPConn, op *ber.Packet) testLDAPResult {
+	dn := testString(op.Children[1])
+	password := testString(op.Children[2])
+	conn.bound = ""
+	if dn == "" {
+		return testSuccess
+	}
+	if strings.EqualFold(dn, s.BindDN) && password == s.BindPassword {
+		conn.bound = dn
+		return testSuccess
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, _ := testDNKeys(dn)
+	if entry, ok := s.entries[key]; ok && password != "" && testContains(entry.GetEqualFoldAttributeValues("userPassword"), password) {
+		conn.bound = entry.DN
+		return testSuccess
+	}
+	return testResult(ldap.LDAPResultInvalidCredentials, "invalid credentials")
+}
+
+func (s *testLDAPServer) search(conn *testLDAPConn, id int64, op *ber.Packet) {
+	base := testString(op.Children[0])
+	scope, _ := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+	attributes := []string{}
+	for _, a := range op.Children[7].Children {
+		attributes = append(attributes, testString(a))
+	}
+
+	s.mutex.Lock()
+	matches := []*ldap.Entry{}
+	if base == "" && scope == ldap.ScopeBaseObject {
+		matches = append(matches, testCopyEntry(s.rootDSE))
+	} else {
+		baseKey, _ := testDNKeys(base)
+		if _, ok := s.entries[baseKey]; !ok {
+			s.mutex.Unlock()
+			result := testResult(ldap.LDAPResultNoSuchObject, "no such object")
+			result.matched = s.matchedDN(base)
+			conn.send(id, result.packet(ldap.ApplicationSearchResultDone))
+			return
+		}
+		keys := make([]string, 0, len(s.entries))
+		for key := range s.entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			entry := s.entries[key]
+			_, parentKey := testDNKeys(entry.DN)
+			inScope := false
+			switch scope {
+			case ldap.ScopeBaseObject:
+				inScope = key == baseKey
+			case ldap.ScopeSingleLevel:
+				inScope = parentKey == baseKey
+			default:
+				inScope = key == baseKey || strings.HasSuffix(key, "\n"+baseKey)
+			}
+			if inScope && testMatchFilter(entry, filter) {
+				matches = append(matches, testCopyEntry(entry))
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, entry := range matches {
+		p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+		p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "objectName"))
+		list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+		for _, attribute := range entry.Attributes {
+			if containsEqualFold(testOperationalAttributes, attribute.Name) {
+				if !testSelectedOperational(attribute.Name, attributes) {
+					continue
+				}
+			} else if !testSelected(attribute.Name, attributes) {
+				continue
+			}
+			list.AppendChild(testAttributePacket(attribute.Name, attribute.Values))
+		}
+		p.AppendChild(list)
+		conn.send(id, p)
+	}
+	conn.send(id, testSuccess.packet(ldap.ApplicationSearchResultDone))
+}
+
+// testSelectedOperational checks whether an operational attribute is among
+// the requested ones.
+func testSelectedOperational(name string, attributes []string) bool {
+	for _, a := range attributes {
+		if a == "+" || strings.EqualFold(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// testSelected checks whether an attribute is among the requested ones.
+func testSelected(name string, attributes []string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+	for _, a := range attributes {
+		if a == "*" || strings.EqualFold(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func testMatchFilter(entry *ldap.Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, f := range filter.Children {
+			if !testMatchFilter(entry, f) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, f := range filter.Children {
+			if testMatchFilter(entry, f) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !testMatchFilter(entry, filter.Children[0])
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+		return testContains(entry.GetEqualFoldAttributeValues(testString(filter.Children[0])), testString(filter.Children[1]))
+	case ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		value := strings.ToLower(testString(filter.Children[1]))
+		for _, v := range entry.GetEqualFoldAttributeValues(testString(filter.Children[0])) {
+			v = strings.ToLower(v)
+			if (filter.Tag == ldap.FilterGreaterOrEqual && v >= value) || (filter.Tag == ldap.FilterLessOrEqual && v <= value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(entry.GetEqualFoldAttributeValues(testString(filter))) > 0
+	case ldap.FilterSubstrings:
+		for _, v := range entry.GetEqualFoldAttributeValues(testString(filter.Children[0])) {
+			if testMatchSubstrings(strings.ToLower(v), filter.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func testMatchSubstrings(value string, substrings []*ber.Packet) bool {
+	for _, sub := range substrings {
+		s := strings.ToLower(testString(sub))
+		switch sub.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, s) {
+				return false
+			}
+			value = value[len(s):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(value, s)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(s):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// add and modify implement the Relax Rules control by allowing operational
+// attributes to be set, and modify the Permissive Modify control by ignoring
+// values that exist already or are gone.
+func (s *testLDAPServer) add(op *ber.Packet, controls []*ber.Packet, identity string) testLDAPResult {
+	_, relax := testControlValue(controls, relaxRulesControlOID)
+	dn := testString(op.Children[0])
+	key, parentKey := testDNKeys(dn)
+	if _, ok := s.entries[key]; ok {
+		return testResult(ldap.LDAPResultEntryAlreadyExists, "entry already exists")
+	}
+	if _, ok := s.entries[parentKey]; !ok {
+		result := testResult(ldap.LDAPResultNoSuchObject, "parent does not exist")
+		result.matched = s.matchedDN(dn)
+		return result
+	}
+
+	entry := &ldap.Entry{DN: dn}
+	for _, a := range op.Children[1].Children {
+		name := testString(a.Children[0])
+		values := []string{}
+		for _, v := range a.Children[1].Children {
+			values = append(values, testString(v))
+		}
+		if len(values) == 0 {
+			return testResult(ldap.LDAPResultProtocolError, "%s: no values", name)
+		}
+		if containsEqualFold(testOperationalAttributes, name) && !relax {
+			return testResult(ldap.LDAPResultConstraintViolation, "%s: no user modification allowed", name)
+		}
+		entry.Attributes = append(entry.Attributes, &ldap.EntryAttribute{Name: name, Values: values})
+	}
+	if len(entry.GetEqualFoldAttributeValues("objectClass")) == 0 {
+		return testResult(ldap.LDAPResultObjectClassViolation, "no objectClass attribute")
+	}
+	testAddNamingValues(entry)
+	s.sequence++
+	now := time.Now().UTC().Format("20060102150405Z")
+	for name, value := range map[string]string{
+		"entryUUID":       fmt.Sprintf("00000000-0000-4000-8000-%012d", s.sequence),
+		"createTimestamp": now,
+		"modifyTimestamp": now,
+		"creatorsName":    identity,
+		"modifiersName":   identity,
+	} {
+		if len(entry.GetEqualFoldAttributeValues(name)) == 0 {
+			testSetValues(entry, name, []string{value})
+		}
+	}
+	s.entries[key] = entry
+	return testSuccess
+}
+
+func (s *testLDAPServer) modify(op *ber.Packet, controls []*ber.Packet, identity string) testLDAPResult {
+	_, relax := testControlValue(controls, relaxRulesControlOID)
+	_, permissive := testControlValue(controls, permissiveModifyControlOID)
+	dn := testString(op.Children[0])
+	key, _ := testDNKeys(dn)
+	original, ok := s.entries[key]
+	if !ok {
+		result := testResult(ldap.LDAPResultNoSuchObject, "no such object")
+		result.matched = s.matchedDN(dn)
+		return result
+	}
+
+	entry := testCopyEntry(original)
+	for _, change := range op.Children[1].Children {
+		operation, _ := change.Children[0].Value.(int64)
+		name := testString(change.Children[1].Children[0])
+		values := []string{}
+		for _, v := range change.Children[1].Children[1].Children {
+			values = append(values, testString(v))
+		}
+		if containsEqualFold(testOperationalAttributes, name) && !relax {
+			return testResult(ldap.LDAPResultConstraintViolation, "%s: no user modification allowed", name)
+		}
+		current := entry.GetEqualFoldAttributeValues(name)
+
+		switch operation {
+		case ldap.AddAttribute:
+			for _, v := range values {
+				if testContains(current, v) {
+					if permissive {
+						continue
+					}
+					return testResult(ldap.LDAPResultAttributeOrValueExists, "%s: value #0 provided more than once", name)
+				}
+				current = append(current, v)
+			}
+			testSetValues(entry, name, current)
+		case ldap.DeleteAttribute:
+			if len(current) == 0 {
+				if permissive {
+					continue
+				}
+				return testResult(ldap.LDAPResultNoSuchAttribute, "%s: no such attribute", name)
+			}
+			if len(values) == 0 {
+				testSetValues(entry, name, nil)
+				continue
+			}
+			remaining := []string{}
+			for _, v := range current {
+				if !testContains(values, v) {
+					remaining = append(remaining, v)
+				}
+			}
+			if len(remaining) != len(current)-len(values) && !permissive {
+				return testResult(ldap.LDAPResultNoSuchAttribute, "%s: no such value", name)
+			}
+			testSetValues(entry, name, remaining)
+		case ldap.ReplaceAttribute:
+			testSetValues(entry, name, values)
+		case ldap.IncrementAttribute:
+			if len(current) != 1 || len(values) != 1 {
+				return testResult(ldap.LDAPResultConstraintViolation, "%s: cannot increment", name)
+			}
+			a, errA := strconv.ParseInt(current[0], 10, 64)
+			b, errB := strconv.ParseInt(values[0], 10, 64)
+			if errA != nil || errB != nil {
+				return testResult(ldap.LDAPResultConstraintViolation, "%s: cannot increment", name)
+			}
+			testSetValues(entry, name, []string{strconv.FormatInt(a+b, 10)})
+		default:
+			return testResult(ldap.LDAPResultProtocolError, "unknown operation %d", operation)
+		}
+	}
+
+	if len(entry.GetEqualFoldAttributeValues("objectClass")) == 0 {
+		return testResult(ldap.LDAPResultObjectClassViolation, "no objectClass attribute")
+	}
+	if !testHasNamingValues(entry) {
+		return testResult(ldap.LDAPResultNotAllowedOnRDN, "cannot remove the naming attribute")
+	}
+	if len(entry.GetEqualFoldAttributeValues("modifyTimestamp")) > 0 {
+		testSetValues(entry, "modifyTimestamp", []string{time.Now().UTC().Format("20060102150405Z")})
+		testSetValues(entry, "modifiersName", []string{identity})
+	}
+	s.entries[key] = entry
+	return testSuccess
+}
+
+func (s *testLDAPServer) del(op *ber.Packet) testLDAPResult {
+	dn := testString(op)
+	key, _ := testDNKeys(dn)
+	if _, ok := s.entries[key]; !ok {
+		result := testResult(ldap.LDAPResultNoSuchObject, "no such object")
+		result.matched = s.matchedDN(dn)
+		return result
+	}
+	for other := range s.entries {
+		if strings.HasSuffix(other, "\n"+key) {
+			return testResult(ldap.LDAPResultNotAllowedOnNonLeaf, "subordinate objects must be deleted first")
+		}
+	}
+	delete(s.entries, key)
+	return testSuccess
+}
+
+func (s *testLDAPServer) modifyDN(op *ber.Packet) testLDAPResult {
+	dn := testString(op.Children[0])
+	newRDN := testString(op.Children[1])
+	deleteOldRDN, _ := op.Children[2].Value.(bool)
+	key, parentKey := testDNKeys(dn)
+	entry, ok := s.entries[key]
+	if !ok {
+		result := testResult(ldap.LDAPResultNoSuchObject, "no such object")
+		result.matched = s.matchedDN(dn)
+		return result
+	}
+
+	_, parent := splitDN(entry.DN)
+	if len(op.Children) > 3 {
+		parent = testString(op.Children[3])
+		parentKey, _ = testDNKeys(parent)
+		if _, ok := s.entries[parentKey]; !ok {
+			return testResult(ldap.LDAPResultNoSuchObject, "new superior does not exist")
+		}
+	}
+	newDN := newRDN
+	if parent != "" {
+		newDN = newRDN + "," + parent
+	}
+	newKey, _ := testDNKeys(newDN)
+	if _, ok := s.entries[newKey]; ok && newKey != key {
+		return testResult(ldap.LDAPResultEntryAlreadyExists, "entry already exists")
+	}
+
+	renamed := testCopyEntry(entry)
+	if deleteOldRDN {
+		for _, ava := range testRDNAttributes(entry.DN) {
+			remaining := []string{}
+			for _, v := range renamed.GetEqualFoldAttributeValues(ava.Type) {
+				if !strings.EqualFold(v, ava.Value) {
+					remaining = append(remaining, v)
+				}
+			}
+			testSetValues(renamed, ava.Type, remaining)
+		}
+	}
+	renamed.DN = newDN
+	testAddNamingValues(renamed)
+
+	// move the subtree along
+	for other, child := range s.entries {
+		if !strings.HasSuffix(other, "\n"+key) {
+			continue
+		}
+		depth := dnDepth(child.DN) - dnDepth(entry.DN)
+		parsed, err := ldap.ParseDN(child.DN)
+		if err != nil {
+			continue
+		}
+		rdns := []string{}
+		for _, rdn := range parsed.RDNs[:depth] {
+			avas := []string{}
+			for _, ava := range rdn.Attributes {
+				avas = append(avas, ava.Type+"="+escapeDNValue(ava.Value))
+			}
+			rdns = append(rdns, strings.Join(avas, "+"))
+		}
+		moved := testCopyEntry(child)
+		moved.DN = strings.Join(rdns, ",") + "," + newDN
+		delete(s.entries, other)
+		movedKey, _ := testDNKeys(moved.DN)
+		s.entries[movedKey] = moved
+	}
+	delete(s.entries, key)
+	s.entries[newKey] = renamed
+	return testSuccess
+}
+
+func (s *testLDAPServer) compare(op *ber.Packet) testLDAPResult {
+	dn := testString(op.Children[0])
+	name := testString(op.Children[1].Children[0])
+	value := testString(op.Children[1].Children[1])
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, _ := testDNKeys(dn)
+	entry, ok := s.entries[key]
+	if !ok {
+		result := testResult(ldap.LDAPResultNoSuchObject, "no such object")
+		result.matched = s.matchedDN(dn)
+		return result
+	}
+	values := entry.GetEqualFoldAttributeValues(name)
+	if len(values) == 0 {
+		return testResult(ldap.LDAPResultNoSuchAttribute, "%s: no such attribute", name)
+	}
+	if testContains(values, value) {
+		return testLDAPResult{code: ldap.LDAPResultCompareTrue}
+	}
+	return testLDAPResult{code: ldap.LDAPResultCompareFalse}
+}
+
+// matchedDN returns the DN of the closest existing ancestor of dn.
+func (s *testLDAPServer) matchedDN(dn string) string {
+	for dn != "" {
+		_, dn = splitDN(dn)
+		key, _ := testDNKeys(dn)
+		if entry, ok := s.entries[key]; ok {
+			return entry.DN
+		}
+	}
+	return ""
+}
+
+// testDNKeys returns normalized keys of the DN and of its parent, with the
+// RDNs separated by newlines.
+func testDNKeys(dn string) (string, string) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn), ""
+	}
+	rdns := []string{}
+	for _, rdn := range parsed.RDNs {
+		avas := []string{}
+		for _, ava := range rdn.Attributes {
+			avas = append(avas, strings.ToLower(ava.Type)+"="+strings.ToLower(ava.Value))
+		}
+		sort.Strings(avas)
+		rdns = append(rdns, strings.Join(avas, "+"))
+	}
+	if len(rdns) == 0 {
+		return "", ""
+	}
+	return strings.Join(rdns, "\n"), strings.Join(rdns[1:], "\n")
+}
+
+func testRDNAttributes(dn string) []*ldap.AttributeTypeAndValue {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return nil
+	}
+	return parsed.RDNs[0].Attributes
+}
+
+// testAddNamingValues adds the values of the RDN to the entry.
+func testAddNamingValues(entry *ldap.Entry) {
+	for _, ava := range testRDNAttributes(entry.DN) {
+		values := entry.GetEqualFoldAttributeValues(ava.Type)
+		if !testContains(values, ava.Value) {
+			testSetValues(entry, ava.Type, append(values, ava.Value))
+		}
+	}
+}
+
+func testHasNamingValues(entry *ldap.Entry) bool {
+	for _, ava := range testRDNAttributes(entry.DN) {
+		if !testContains(entry.GetEqualFoldAttributeValues(ava.Type), ava.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// testSetValues replaces the values of an attribute, removing it if there
+// are none.
+func testSetValues(entry *ldap.Entry, name string, values []string) {
+	attributes := []*ldap.EntryAttribute{}
+	found := false
+	for _, a := range entry.Attributes {
+		if !strings.EqualFold(a.Name, name) {
+			attributes = append(attributes, a)
+			continue
+		}
+		found = true
+		if len(values) > 0 {
+			attributes = append(attributes, &ldap.EntryAttribute{Name: a.Name, Values: values})
+		}
+	}
+	if !found && len(values) > 0 {
+		attributes = append(attributes, &ldap.EntryAttribute{Name: name, Values: values})
+	}
+	entry.Attributes = attributes
+}
+
+func testCopyEntry(entry *ldap.Entry) *ldap.Entry {
+	c := &ldap.Entry{DN: entry.DN}
+	for _, a := range entry.Attributes {
+		c.Attributes = append(c.Attributes, &ldap.EntryAttribute{Name: a.Name, Values: append([]string{}, a.Values...)})
+	}
+	return c
+}
+
+func testAttributePacket(name string, values []string) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+	set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+	for _, v := range values {
+		set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+	}
+	p.AppendChild(set)
+	return p
+}
+
+// testString returns the content of a primitive packet as string.
+func testString(p *ber.Packet) string {
+	return p.Data.String()
+}
+
+func testContains(values []string, value string) bool {
+	return containsEqualFold(values, value)
+}
+
+func TestLDAPServer(t *testing.T) {
+	s := newTestLDAPServer(t, "dc=example,dc=com")
+	conn, err := ldap.DialURL(s.URL)
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(s.BindDN, "wrong"); !isLDAPResultCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Errorf("Expected invalid credentials, got %v", err)
+	}
+	if err := conn.Bind(s.BindDN, s.BindPassword); err != nil {
+		t.Fatalf("Unable to bind: %v", err)
+	}
+
+	add := ldap.NewAddRequest("ou=people,dc=example,dc=com", nil)
+	add.Attribute("objectClass", []string{"organizationalUnit"})
+	if err := conn.Add(add); err != nil {
+		t.Fatalf("Unable to add: %v", err)
+	}
+	if err := conn.Add(add); !isLDAPResultCode(err, ldap.LDAPResultEntryAlreadyExists) {
+		t.Errorf("Expected entry already exists, got %v", err)
+	}
+	add = ldap.NewAddRequest("cn=jdoe,ou=missing,dc=example,dc=com", nil)
+	add.Attribute("objectClass", []string{"person"})
+	if err := conn.Add(add); !isLDAPResultCode(err, ldap.LDAPResultNoSuchObject) {
+		t.Errorf("Expected no such object, got %v", err)
+	}
+	add = ldap.NewAddRequest("cn=jdoe,ou=people,dc=example,dc=com", nil)
+	add.Attribute("objectClass", []string{"person"})
+	add.Attribute("sn", []string{"Doe"})
+	if err := conn.Add(add); err != nil {
+		t.Fatalf("Unable to add: %v", err)
+	}
+	if values := s.Values("cn=jdoe,ou=people,dc=example,dc=com", "cn"); !testContains(values, "jdoe") {
+		t.Errorf("Expected the naming attribute to be added, got %v", values)
+	}
+
+	modify := ldap.NewModifyRequest("cn=jdoe,ou=people,dc=example,dc=com", nil)
+	modify.Add("mail", []string{"jdoe@example.com"})
+	modify.Replace("sn", []string{"Smith"})
+	if err := conn.Modify(modify); err != nil {
+		t.Fatalf("Unable to modify: %v", err)
+	}
+	modify = ldap.NewModifyRequest("cn=jdoe,ou=people,dc=example,dc=com", nil)
+	modify.Delete("description", []string{})
+	if err := conn.Modify(modify); !isLDAPResultCode(err, ldap.LDAPResultNoSuchAttribute) {
+		t.Errorf("Expected no such attribute, got %v", err)
+	}
+
+	sr, err := conn.Search(ldap.NewSearchRequest("dc=example,dc=com", ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		"(&(objectClass=person)(mail=*@example.com)(!(sn=Doe)))", []string{"sn", "mail"}, nil))
+	if err != nil {
+		t.Fatalf("Unable to search: %v", err)
+	}
+	if len(sr.Entries) != 1 || sr.Entries[0].GetAttributeValue("sn") != "Smith" || sr.Entries[0].GetAttributeValue("cn") != "" {
+		t.Errorf("Unexpected search result %v", sr.Entries)
+	}
+
+	if ok, err := conn.Compare("cn=jdoe,ou=people,dc=example,dc=com", "sn", "smith"); err != nil || !ok {
+		t.Errorf("Expected the comparison to succeed, got %v, %v", ok, err)
+	}
+
+	if err := conn.Del(ldap.NewDelRequest("ou=people,dc=example,dc=com", nil)); !isLDAPResultCode(err, ldap.LDAPResultNotAllowedOnNonLeaf) {
+		t.Errorf("Expected not allowed on non-leaf, got %v", err)
+	}
+	if err := conn.ModifyDN(ldap.NewModifyDNRequest("ou=people,dc=example,dc=com", "ou=users", true, "")); err != nil {
+		t.Fatalf("Unable to rename: %v", err)
+	}
+	if s.Entry("cn=jdoe,ou=users,dc=example,dc=com") == nil {
+		t.Errorf("Expected the subtree to be moved")
+	}
+	if err := conn.Del(ldap.NewDelRequest("cn=jdoe,ou=users,dc=example,dc=com", nil)); err != nil {
+		t.Errorf("Unable to delete: %v", err)
+	}
+}
@@ -0,0 +1,450 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// the change types an LDIF record can have (RFC 2849); content records have
+// no change type and are treated like add records.
+const (
+	ldifChangeTypeAdd    = "add"
+	ldifChangeTypeDelete = "delete"
+	ldifChangeTypeModify = "modify"
+	ldifChangeTypeModRDN = "modrdn"
+	ldifChangeTypeModDN  = "moddn"
+)
+
+// ldifRecord is a single content or change record of an LDIF document.
+type ldifRecord struct {
+	DN         string
+	ChangeType string
+
+	// Attributes holds the attributes of content and add records, in the
+	// order in which they appear.
+	Attributes []ldap.Attribute
+
+	// Changes holds the modifications of modify records.
+	Changes []ldap.Change
+
+	// NewRDN, DeleteOldRDN and NewSuperior describe modrdn/moddn records.
+	NewRDN       string
+	DeleteOldRDN bool
+	NewSuperior  string
+}
+
+// IsAdd checks whether the record creates an entry, i.e. whether it is a
+// content or an add record.
+func (r *ldifRecord) IsAdd() bool {
+	return r.ChangeType == "" || r.ChangeType == ldifChangeTypeAdd
+}
+
+// addAttribute appends a value to the attribute with the given name, keeping
+// the order in which attributes first appear.
+func (r *ldifRecord) addAttribute(name, value string) {
+	for i := range r.Attributes {
+		if strings.EqualFold(r.Attributes[i].Type, name) {
+			r.Attributes[i].Vals = append(r.Attributes[i].Vals, value)
+			return
+		}
+	}
+	r.Attributes = append(r.Attributes, ldap.Attribute{Type: name, Vals: []string{value}})
+}
+
+// ldifLine is an unfolded "name: value" line of an LDIF record.
+type ldifLine struct {
+	number int
+	name   string
+	value  string
+}
+
+// parseLDIF parses an LDIF document (RFC 2849) into its records; URL values
+// ("name:< url") and controls are not supported.
+func parseLDIF(content string) ([]*ldifRecord, error) {
+	blocks, err := splitLDIF(content)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*ldifRecord{}
+	for _, block := range blocks {
+		if len(block) == 1 && strings.EqualFold(block[0].name, "version") {
+			continue
+		}
+		if strings.EqualFold(block[0].name, "version") {
+			block = block[1:]
+		}
+		record, err := parseLDIFRecord(block)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// splitLDIF unfolds the lines of an LDIF document, drops comments and
+// groups the lines into records separated by empty lines.
+func splitLDIF(content string) ([][]ldifLine, error) {
+	blocks := [][]ldifLine{}
+	current := []ldifLine{}
+	raw := []string{}
+	rawNumbers := []int{}
+
+	flush := func() error {
+		for i, l := range raw {
+			line, err := parseLDIFLine(rawNumbers[i], l)
+			if err != nil {
+				return err
+			}
+			current = append(current, line)
+		}
+		raw, rawNumbers = raw[:0], rawNumbers[:0]
+		if len(current) > 0 {
+			blocks = append(blocks, current)
+			current = []ldifLine{}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	number := 0
+	inComment := false
+	for scanner.Scan() {
+		number++
+		l := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.HasPrefix(l, " "):
+			// continuation of the previous line (or comment)
+			if inComment {
+				continue
+			}
+			if len(raw) == 0 {
+				return nil, fmt.Errorf("LDIF line %d: continuation without a preceding line", number)
+			}
+			raw[len(raw)-1] += l[1:]
+		case strings.HasPrefix(l, "#"):
+			inComment = true
+		case strings.TrimSpace(l) == "":
+			inComment = false
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			inComment = false
+			raw = append(raw, l)
+			rawNumbers = append(rawNumbers, number)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func parseLDIFLine(number int, l string) (ldifLine, error) {
+	if l == "-" {
+		return ldifLine{number: number, name: "-"}, nil
+	}
+	i := strings.IndexByte(l, ':')
+	if i <= 0 {
+		return ldifLine{}, fmt.Errorf("LDIF line %d: expected \"name: value\", got %q", number, l)
+	}
+	line := ldifLine{number: number, name: l[:i]}
+	rest := l[i+1:]
+	switch {
+	case strings.HasPrefix(rest, ":"):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(rest[1:]))
+		if err != nil {
+			return ldifLine{}, fmt.Errorf("LDIF line %d: invalid base64 value for %q: %v", number, line.name, err)
+		}
+		line.value = string(decoded)
+	case strings.HasPrefix(rest, "<"):
+		return ldifLine{}, fmt.Errorf("LDIF line %d: URL values are not supported", number)
+	default:
+		line.value = strings.TrimLeft(rest, " ")
+	}
+	return line, nil
+}
+
+func parseLDIFRecord(lines []ldifLine) (*ldifRecord, error) {
+	if !strings.EqualFold(lines[0].name, "dn") {
+		return nil, fmt.Errorf("LDIF line %d: expected the record to start with \"dn\", got %q", lines[0].number, lines[0].name)
+	}
+	record := &ldifRecord{DN: lines[0].value}
+	lines = lines[1:]
+
+	if len(lines) > 0 && strings.EqualFold(lines[0].name, "control") {
+		return nil, fmt.Errorf("LDIF line %d: controls are not supported", lines[0].number)
+	}
+	if len(lines) > 0 && strings.EqualFold(lines[0].name, "changetype") {
+		record.ChangeType = strings.ToLower(lines[0].value)
+		lines = lines[1:]
+	}
+
+	switch record.ChangeType {
+	case "", ldifChangeTypeAdd:
+		for _, l := range lines {
+			if l.name == "-" {
+				return nil, fmt.Errorf("LDIF line %d: unexpected \"-\" in %q", l.number, record.DN)
+			}
+			record.addAttribute(l.name, l.value)
+		}
+		if len(record.Attributes) == 0 {
+			return nil, fmt.Errorf("LDIF record %q has no attributes", record.DN)
+		}
+	case ldifChangeTypeDelete:
+		if len(lines) > 0 {
+			return nil, fmt.Errorf("LDIF line %d: unexpected content in delete record %q", lines[0].number, record.DN)
+		}
+	case ldifChangeTypeModify:
+		for len(lines) > 0 {
+			op := lines[0]
+			var change ldap.Change
+			switch strings.ToLower(op.name) {
+			case "add":
+				change.Operation = ldap.AddAttribute
+			case "delete":
+				change.Operation = ldap.DeleteAttribute
+			case "replace":
+				change.Operation = ldap.ReplaceAttribute
+			default:
+				return nil, fmt.Errorf("LDIF line %d: unsupported modify operation %q in %q", op.number, op.name, record.DN)
+			}
+			change.Modification = ldap.PartialAttribute{Type: op.value, Vals: []string{}}
+			lines = lines[1:]
+			for len(lines) > 0 && lines[0].name != "-" {
+				if !strings.EqualFold(lines[0].name, op.value) {
+					return nil, fmt.Errorf("LDIF line %d: expected a value of %q, got %q", lines[0].number, op.value, lines[0].name)
+				}
+				change.Modification.Vals = append(change.Modification.Vals, lines[0].value)
+				lines = lines[1:]
+			}
+			if len(lines) > 0 {
+				lines = lines[1:] // skip the "-" separator
+			}
+			record.Changes = append(record.Changes, change)
+		}
+	case ldifChangeTypeModRDN, ldifChangeTypeModDN:
+		record.DeleteOldRDN = true
+		for _, l := range lines {
+			switch strings.ToLower(l.name) {
+			case "newrdn":
+				record.NewRDN = l.value
+			case "deleteoldrdn":
+				v, err := strconv.Atoi(l.value)
+				if err != nil || (v != 0 && v != 1) {
+					return nil, fmt.Errorf("LDIF line %d: deleteoldrdn must be 0 or 1, got %q", l.number, l.value)
+				}
+				record.DeleteOldRDN = v == 1
+			case "newsuperior":
+				record.NewSuperior = l.value
+			default:
+				return nil, fmt.Errorf("LDIF line %d: unexpected %q in modrdn record %q", l.number, l.name, record.DN)
+			}
+		}
+		if record.NewRDN == "" {
+			return nil, fmt.Errorf("LDIF record %q is missing newrdn", record.DN)
+		}
+	default:
+		return nil, fmt.Errorf("LDIF record %q has unsupported changetype %q", record.DN, record.ChangeType)
+	}
+	return record, nil
+}
+
+// applyLDIFRecord sends the request described by the record to the server.
+func applyLDIFRecord(ctx context.Context, client *Client, record *ldifRecord) error {
+	switch record.ChangeType {
+	case "", ldifChangeTypeAdd:
+		request := ldap.NewAddRequest(record.DN, []ldap.Control{})
+		for _, attribute := range record.Attributes {
+			request.Attribute(attribute.Type, attribute.Vals)
+		}
+		return client.Add(ctx, request)
+	case ldifChangeTypeDelete:
+		return client.Del(ctx, ldap.NewDelRequest(record.DN, nil))
+	case ldifChangeTypeModify:
+		request := ldap.NewModifyRequest(record.DN, []ldap.Control{})
+		request.Changes = append(request.Changes, record.Changes...)
+		return client.Modify(ctx, request)
+	case ldifChangeTypeModRDN, ldifChangeTypeModDN:
+		return client.ModifyDN(ctx, ldap.NewModifyDNRequest(record.DN, record.NewRDN, record.DeleteOldRDN, record.NewSuperior))
+	}
+	return fmt.Errorf("Unsupported changetype %q", record.ChangeType)
+}
+
+// the attributes whose values are not written to LDIF output
+var ldifRedactedAttributes = []string{"userPassword", "unicodePwd", "sambaNTPassword", "sambaLMPassword", "krbPrincipalKey"}
+
+// ldifRecordFromRequest describes an add, modify, delete or modify DN request
+// as an LDIF change record.
+func ldifRecordFromRequest(request interface{}) *ldifRecord {
+	switch r := request.(type) {
+	case *ldap.AddRequest:
+		return &ldifRecord{DN: r.DN, ChangeType: ldifChangeTypeAdd, Attributes: r.Attributes}
+	case *ldap.ModifyRequest:
+		return &ldifRecord{DN: r.DN, ChangeType: ldifChangeTypeModify, Changes: r.Changes}
+	case *ldap.DelRequest:
+		return &ldifRecord{DN: r.DN, ChangeType: ldifChangeTypeDelete}
+	case *ldap.ModifyDNRequest:
+		changeType := ldifChangeTypeModRDN
+		if r.NewSuperior != "" {
+			changeType = ldifChangeTypeModDN
+		}
+		return &ldifRecord{DN: r.DN, ChangeType: changeType, NewRDN: r.NewRDN, DeleteOldRDN: r.DeleteOldRDN, NewSuperior: r.NewSuperior}
+	}
+	return nil
+}
+
+// render writes the record as an RFC 2849 change record, including the given
+// controls; the values of the redacted attributes are replaced by a comment.
+func (r *ldifRecord) render(controls []ldap.Control, redacted []string) string {
+	var b strings.Builder
+	writeLDIFLine(&b, "dn", r.DN)
+	for _, control := range controls {
+		writeLDIFLine(&b, "control", ldifControl(control))
+	}
+	changeType := r.ChangeType
+	if changeType == "" {
+		changeType = ldifChangeTypeAdd
+	}
+	writeLDIFLine(&b, "changetype", changeType)
+
+	writeValues := func(name string, values []string) {
+		if len(values) > 0 && containsEqualFold(redacted, name) {
+			fmt.Fprintf(&b, "# %d value(s) of %s redacted\n", len(values), name)
+			return
+		}
+		for _, value := range values {
+			writeLDIFLine(&b, name, value)
+		}
+	}
+
+	switch changeType {
+	case ldifChangeTypeAdd:
+		for _, attribute := range r.Attributes {
+			writeValues(attribute.Type, attribute.Vals)
+		}
+	case ldifChangeTypeModify:
+		operations := map[uint]string{
+			ldap.AddAttribute:       "add",
+			ldap.DeleteAttribute:    "delete",
+			ldap.ReplaceAttribute:   "replace",
+			ldap.IncrementAttribute: "increment",
+		}
+		for _, change := range r.Changes {
+			writeLDIFLine(&b, operations[change.Operation], change.Modification.Type)
+			writeValues(change.Modification.Type, change.Modification.Vals)
+			b.WriteString("-\n")
+		}
+	case ldifChangeTypeModRDN, ldifChangeTypeModDN:
+		writeLDIFLine(&b, "newrdn", r.NewRDN)
+		if r.DeleteOldRDN {
+			writeLDIFLine(&b, "deleteoldrdn", "1")
+		} else {
+			writeLDIFLine(&b, "deleteoldrdn", "0")
+		}
+		if r.NewSuperior != "" {
+			writeLDIFLine(&b, "newsuperior", r.NewSuperior)
+		}
+	}
+	return b.String()
+}
+
+// ldifControl renders a control as the value of a "control" line (RFC 2849):
+// its OID, its criticality and its value, if any, base64-encoded.
+func ldifControl(control ldap.Control) string {
+	packet := control.Encode()
+	criticality := "false"
+	value := ""
+	for _, child := range packet.Children[1:] {
+		switch child.Tag {
+		case ber.TagBoolean:
+			if critical, ok := child.Value.(bool); ok && critical {
+				criticality = "true"
+			}
+		case ber.TagOctetString:
+			value = ":: " + base64.StdEncoding.EncodeToString(child.Data.Bytes())
+		}
+	}
+	return control.GetControlType() + " " + criticality + value
+}
+
+// the maximum length of an LDIF line, longer ones are folded
+const ldifLineLength = 76
+
+// writeLDIFLine writes a "name: value" line, base64-encoding values that are
+// not safe strings (RFC 2849) and folding long lines.
+func writeLDIFLine(b *strings.Builder, name, value string) {
+	line := name + ": " + value
+	if !isLDIFSafeString(value) {
+		line = name + ":: " + base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	width := ldifLineLength
+	for len(line) > width {
+		b.WriteString(line[:width])
+		b.WriteString("\n ")
+		line = line[width:]
+		// continuation lines start with a space
+		width = ldifLineLength - 1
+	}
+	b.WriteString(line)
+	b.WriteRune('\n')
+}
+
+// isLDIFSafeString checks whether the value can be written as is, i.e.
+// whether it is a SAFE-STRING of RFC 2849 that doesn't end with a space.
+func isLDIFSafeString(value string) bool {
+	if value == "" {
+		return true
+	}
+	switch value[0] {
+	case ' ', ':', '<':
+		return false
+	}
+	if value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c == 0 || c == '\n' || c == '\r' || c > 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// ldifFile is the LDIF output written to the file at path: the file is only
+// created, replacing the output of earlier runs, once the first request is
+// written, and synced after every write, as the provider is stopped without
+// notice.
+type ldifFile struct {
+	path string
+	file *os.File
+}
+
+func (f *ldifFile) Write(b []byte) (int, error) {
+	if f.file == nil {
+		file, err := os.Create(f.path)
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+	}
+	n, err := f.file.Write(b)
+	if err == nil {
+		err = f.file.Sync()
+	}
+	return n, err
+}
@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestParseLDIFContentRecords(t *testing.T) {
+	records, err := parseLDIF(`version: 1
+
+# the people
+dn: ou=people,dc=example,dc=com
+objectClass: organizationalUnit
+ou: people
+
+dn: cn=jdoe,ou=people,dc=example,dc=com
+objectClass: inetOrgPerson
+objectClass: person
+cn: jdoe
+sn: Doe
+description: a long descr
+ iption
+displayName:: Sm9obiBEb2U=
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Invalid number of records, expected 2 got %d", len(records))
+	}
+	r := records[1]
+	if r.DN != "cn=jdoe,ou=people,dc=example,dc=com" || !r.IsAdd() {
+		t.Errorf("Invalid record %+v", r)
+	}
+	expected := []ldap.Attribute{
+		{Type: "objectClass", Vals: []string{"inetOrgPerson", "person"}},
+		{Type: "cn", Vals: []string{"jdoe"}},
+		{Type: "sn", Vals: []string{"Doe"}},
+		{Type: "description", Vals: []string{"a long description"}},
+		{Type: "displayName", Vals: []string{"John Doe"}},
+	}
+	if !reflect.DeepEqual(r.Attributes, expected) {
+		t.Errorf("Invalid attributes, got %+v", r.Attributes)
+	}
+}
+
+func TestParseLDIFChangeRecords(t *testing.T) {
+	records, err := parseLDIF(`dn: cn=jdoe,ou=people,dc=example,dc=com
+changetype: modify
+add: mail
+mail: jdoe@example.com
+-
+delete: description
+-
+replace: sn
+sn: Doe
+sn: Smith
+-
+
+dn: cn=jdoe,ou=people,dc=example,dc=com
+changetype: modrdn
+newrdn: cn=jsmith
+deleteoldrdn: 0
+newsuperior: ou=staff,dc=example,dc=com
+
+dn: cn=old,dc=example,dc=com
+changetype: delete
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Invalid number of records, expected 3 got %d", len(records))
+	}
+
+	modify := records[0]
+	if modify.ChangeType != ldifChangeTypeModify || len(modify.Changes) != 3 {
+		t.Fatalf("Invalid modify record %+v", modify)
+	}
+	if modify.Changes[0].Operation != ldap.AddAttribute || !reflect.DeepEqual(modify.Changes[0].Modification.Vals, []string{"jdoe@example.com"}) {
+		t.Errorf("Invalid add change %+v", modify.Changes[0])
+	}
+	if modify.Changes[1].Operation != ldap.DeleteAttribute || len(modify.Changes[1].Modification.Vals) != 0 {
+		t.Errorf("Invalid delete change %+v", modify.Changes[1])
+	}
+	if modify.Changes[2].Operation != ldap.ReplaceAttribute || !reflect.DeepEqual(modify.Changes[2].Modification.Vals, []string{"Doe", "Smith"}) {
+		t.Errorf("Invalid replace change %+v", modify.Changes[2])
+	}
+
+	modrdn := records[1]
+	if modrdn.NewRDN != "cn=jsmith" || modrdn.DeleteOldRDN || modrdn.NewSuperior != "ou=staff,dc=example,dc=com" {
+		t.Errorf("Invalid modrdn record %+v", modrdn)
+	}
+
+	if records[2].ChangeType != ldifChangeTypeDelete || records[2].IsAdd() {
+		t.Errorf("Invalid delete record %+v", records[2])
+	}
+}
+
+func TestParseInvalidLDIF(t *testing.T) {
+	for _, content := range []string{
+		"objectClass: top\n",
+		"dn: cn=x\n",
+		"dn: cn=x\nchangetype: rename\n",
+		"dn: cn=x\nchangetype: modify\nadd: mail\nsn: Doe\n",
+		"dn: cn=x\nchangetype: modrdn\ndeleteoldrdn: 1\n",
+		"dn: cn=x\njpegPhoto:< file:///tmp/photo.jpg\n",
+	} {
+		if _, err := parseLDIF(content); err == nil {
+			t.Errorf("Expected an error parsing %q", content)
+		}
+	}
+}
+
+func TestRenderLDIFRecords(t *testing.T) {
+	add := ldap.NewAddRequest("cn=jdoe,dc=example,dc=com", nil)
+	add.Attribute("objectClass", []string{"inetOrgPerson"})
+	add.Attribute("sn", []string{"Doe"})
+	add.Attribute("displayName", []string{"Jöhn Doe"})
+	add.Attribute("description", []string{strings.Repeat("a long description ", 10)})
+	add.Attribute("userPassword", []string{"secret"})
+
+	modify := ldap.NewModifyRequest("cn=jdoe,dc=example,dc=com", nil)
+	modify.Replace("sn", []string{"Smith"})
+	modify.Delete("description", []string{})
+	modify.Add("mail", []string{"jdoe@example.com", " leading space"})
+
+	requests := []interface{}{
+		add,
+		modify,
+		ldap.NewModifyDNRequest("cn=jdoe,dc=example,dc=com", "cn=jsmith", true, "ou=people,dc=example,dc=com"),
+		ldap.NewDelRequest("cn=jsmith,ou=people,dc=example,dc=com", nil),
+	}
+
+	var b strings.Builder
+	b.WriteString("version: 1\n")
+	for _, request := range requests {
+		b.WriteString("\n")
+		b.WriteString(ldifRecordFromRequest(request).render(nil, ldifRedactedAttributes))
+	}
+	content := b.String()
+
+	if strings.Contains(content, "secret") {
+		t.Errorf("Expected the password to be redacted, got\n%s", content)
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if len(line) > 76 {
+			t.Errorf("Expected lines to be folded, got %q", line)
+		}
+	}
+
+	records, err := parseLDIF(content)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing\n%s\n%v", content, err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 records, got %d", len(records))
+	}
+	expectedAttributes := []ldap.Attribute{
+		{Type: "objectClass", Vals: []string{"inetOrgPerson"}},
+		{Type: "sn", Vals: []string{"Doe"}},
+		{Type: "displayName", Vals: []string{"Jöhn Doe"}},
+		{Type: "description", Vals: []string{strings.Repeat("a long description ", 10)}},
+	}
+	if !reflect.DeepEqual(records[0].Attributes, expectedAttributes) {
+		t.Errorf("Unexpected attributes %v", records[0].Attributes)
+	}
+	if !reflect.DeepEqual(records[1].Changes, modify.Changes) {
+		t.Errorf("Unexpected changes %v", records[1].Changes)
+	}
+	if records[2].ChangeType != ldifChangeTypeModDN || records[2].NewRDN != "cn=jsmith" || !records[2].DeleteOldRDN || records[2].NewSuperior != "ou=people,dc=example,dc=com" {
+		t.Errorf("Unexpected modify DN record %+v", records[2])
+	}
+	if records[3].ChangeType != ldifChangeTypeDelete || records[3].DN != "cn=jsmith,ou=people,dc=example,dc=com" {
+		t.Errorf("Unexpected delete record %+v", records[3])
+	}
+}
+
+func TestRenderLDIFControls(t *testing.T) {
+	del := ldap.NewDelRequest("cn=jdoe,dc=example,dc=com", nil)
+	controls := []ldap.Control{
+		ldap.NewControlString(proxiedAuthorizationControlOID, true, "dn:cn=admin,dc=example,dc=com"),
+		ldap.NewControlManageDsaIT(false),
+	}
+	expected := `dn: cn=jdoe,dc=example,dc=com
+control: 2.16.840.1.113730.3.4.18 true:: ZG46Y249YWRtaW4sZGM9ZXhhbXBsZSxkYz1
+ jb20=
+control: 2.16.840.1.113730.3.4.2 false
+changetype: delete
+`
+	if actual := ldifRecordFromRequest(del).render(controls, nil); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestLDIFFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.ldif")
+	if err := os.WriteFile(path, []byte("earlier run\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the output of earlier runs is kept until a request is written
+	client := &Client{}
+	client.SetLDIFOutput(&ldifFile{path: path}, false)
+	if content, _ := os.ReadFile(path); string(content) != "earlier run\n" {
+		t.Errorf("Expected the file to be kept, got %q", content)
+	}
+
+	for _, dn := range []string{"cn=a,dc=example,dc=com", "cn=b,dc=example,dc=com"} {
+		if _, err := client.record(context.Background(), ldap.NewDelRequest(dn, nil), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "version: 1\n\ndn: cn=a,dc=example,dc=com\nchangetype: delete\n\ndn: cn=b,dc=example,dc=com\nchangetype: delete\n"
+	if content, _ := os.ReadFile(path); string(content) != expected {
+		t.Errorf("Unexpected output %q", content)
+	}
+}
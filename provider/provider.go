@@ -42,13 +42,71 @@ func New(version string) func() *schema.Provider {
 					Sensitive:   true,
 					DefaultFunc: schema.EnvDefaultFunc("LDAP_BIND_PASSWORD", nil),
 				},
+				"base_dn": {
+					Type:        schema.TypeString,
+					Description: "The DN that relative DNs of objects are located under, also available as the `${base}` placeholder (written `$${base}` in HCL); see the README for the DNs it applies to.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("LDAP_BASE_DN", ""),
+				},
+				"default_skip_attributes": {
+					Type:        schema.TypeSet,
+					Description: "A list of attributes which will not be tracked by any object, in addition to their own skip_attributes (e.g. pwdChangedTime, lastLogon).",
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Set:         schema.HashString,
+					Optional:    true,
+				},
+				"use_transactions": {
+					Type:        schema.TypeBool,
+					Description: "Apply the changes of ldap_entries and ldap_ldif atomically as LDAP transactions (RFC 5805), if the server supports them.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("LDAP_USE_TRANSACTIONS", false),
+				},
+				"permissive_modify": {
+					Type:        schema.TypeBool,
+					Description: "Send every modify with the Permissive Modify control, so that adding existing values and deleting missing ones succeed.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("LDAP_PERMISSIVE_MODIFY", false),
+				},
+				"relax_rules": {
+					Type:        schema.TypeBool,
+					Description: "Send every add and modify with the (OpenLDAP) Relax Rules control, so that operational attributes can be set; the bind user needs manage access.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("LDAP_RELAX_RULES", false),
+				},
+				"proxied_authorization": {
+					Type:         schema.TypeString,
+					Description:  "The authorization identity (`dn:<DN>` or `u:<user>`) to perform every request as, with the Proxied Authorization control (RFC 4370); resources can override it.",
+					Optional:     true,
+					DefaultFunc:  schema.EnvDefaultFunc("LDAP_PROXIED_AUTHORIZATION", ""),
+					ValidateFunc: validateAuthzID,
+				},
+				"ldif_output_path": {
+					Type:        schema.TypeString,
+					Description: "The path of a file to write the requests changing the directory to as LDIF change records (RFC 2849), with passwords and sensitive values redacted; each provider configuration needs a path of its own.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("LDAP_LDIF_OUTPUT_PATH", ""),
+				},
+				"dry_run": {
+					Type:         schema.TypeBool,
+					Description:  "Only write the requests changing the directory to `ldif_output_path` without sending them; applies then fail, leaving the state alone.",
+					Optional:     true,
+					DefaultFunc:  schema.EnvDefaultFunc("LDAP_DRY_RUN", false),
+					RequiredWith: []string{"ldif_output_path"},
+				},
 			},
 			ResourcesMap: map[string]*schema.Resource{
 				"ldap_object":            resourceLDAPObject(),
 				"ldap_object_attributes": resourceLDAPObjectAttributes(),
+				"ldap_schema":            resourceLDAPSchema(),
+				"ldap_olc_access":        resourceLDAPOlcAccess(),
+				"ldap_ldif":              resourceLDAPLDIF(),
+				"ldap_entries":           resourceLDAPEntries(),
 			},
 			DataSourcesMap: map[string]*schema.Resource{
-				"ldap_object": dataLDAPObject(),
+				"ldap_object":  dataLDAPObject(),
+				"ldap_schema":  dataLDAPSchema(),
+				"ldap_whoami":  dataLDAPWhoAmI(),
+				"ldap_compare": dataLDAPCompare(),
 			},
 			ConfigureContextFunc: providerConfigure,
 		}
@@ -91,6 +149,10 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}
 		}
 	}
 
+	// requests whose context has no deadline, e.g. those of the provider
+	// itself, still give up eventually
+	l.SetTimeout(defaultTimeout)
+
 	err = l.Bind(bindUser, bindPassword)
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
@@ -101,7 +163,42 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}
 		return nil, diags
 	}
 
-	return l, diags
+	client := NewClient(l)
+	client.SetDefaults(d.Get("base_dn").(string), interfacesToStrings(d.Get("default_skip_attributes").(*schema.Set).List()))
+	if d.Get("base_dn").(string) != "" {
+		// without the suffixes, absolute DNs outside of base_dn would be
+		// taken as relative ones
+		if err := client.LoadSuffixes(ctx); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Failed to read the suffixes of the directory",
+				Detail:   fmt.Sprintf("Reading the naming contexts of the root DSE, which base_dn requires, failed with: %v", err),
+			})
+			return nil, diags
+		}
+	}
+
+	client.SetWriteOptions(d.Get("permissive_modify").(bool), d.Get("relax_rules").(bool))
+	client.SetProxiedAuthorization(d.Get("proxied_authorization").(string))
+	if authzID, err := client.WhoAmI(ctx); err != nil {
+		debugLog("provider - unable to determine the authorization identity: %v", err)
+	} else {
+		debugLog("provider - operating as %q", authzID)
+	}
+
+	if d.Get("use_transactions").(bool) && !client.EnableTransactions(ctx) {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Transactions are not supported",
+			Detail:   "The server does not advertise support for LDAP transactions (RFC 5805), so changes are applied without them.",
+		})
+	}
+
+	if path := d.Get("ldif_output_path").(string); path != "" {
+		client.SetLDIFOutput(&ldifFile{path: path}, d.Get("dry_run").(bool))
+	}
+
+	return client, diags
 }
 
 func leveledLog(level string) func(format string, v ...interface{}) {
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const testBaseDN = "dc=example,dc=com"
+
+func TestProvider(t *testing.T) {
+	if err := New("dev")().InternalValidate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// testAccProviderFactories are the provider factories of the acceptance
+// tests, which run the provider in-process.
+var testAccProviderFactories = map[string]func() (*schema.Provider, error){
+	"ldap": func() (*schema.Provider, error) {
+		return New("dev")(), nil
+	},
+}
+
+// testAccProviderConfig renders the provider block connecting to the server.
+func testAccProviderConfig(s *testLDAPServer) string {
+	return fmt.Sprintf(`
+provider "ldap" {
+  url           = %q
+  bind_user     = %q
+  bind_password = %q
+}
+`, s.URL, s.BindDN, s.BindPassword)
+}
+
+// testProviderMeta configures the provider against the server and returns
+// the client handed to resources and data sources.
+func testProviderMeta(t *testing.T, s *testLDAPServer) interface{} {
+	t.Helper()
+	p := New("dev")()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"url":           s.URL,
+		"bind_user":     s.BindDN,
+		"bind_password": s.BindPassword,
+	}))
+	if diags.HasError() {
+		t.Fatalf("Unable to configure the provider: %v", diags)
+	}
+	return p.Meta()
+}
+
+// testApply plans the configuration of a resource against the given state
+// (nil for a new resource) and applies the changes, like Terraform does;
+// the new state is returned.
+func testApply(t *testing.T, r *schema.Resource, state *terraform.InstanceState, raw map[string]interface{}, meta interface{}) *terraform.InstanceState {
+	t.Helper()
+	ctx := context.Background()
+	diff, err := r.Diff(ctx, state, terraform.NewResourceConfigRaw(raw), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	if diff == nil || diff.Empty() {
+		return state
+	}
+	newState, diags := r.Apply(ctx, state, diff, meta)
+	if diags.HasError() {
+		t.Fatalf("Unable to apply: %v", diags)
+	}
+	return newState
+}
+
+// testPlanIsEmpty checks whether applying the configuration to the state
+// changes nothing.
+func testPlanIsEmpty(t *testing.T, r *schema.Resource, state *terraform.InstanceState, raw map[string]interface{}, meta interface{}) bool {
+	t.Helper()
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	return diff == nil || diff.Empty()
+}
+
+// testRefresh reads the resource, returning nil if it is gone.
+func testRefresh(t *testing.T, r *schema.Resource, state *terraform.InstanceState, meta interface{}) *terraform.InstanceState {
+	t.Helper()
+	newState, diags := r.RefreshWithoutUpgrade(context.Background(), state, meta)
+	if diags.HasError() {
+		t.Fatalf("Unable to refresh: %v", diags)
+	}
+	return newState
+}
+
+// testDestroy deletes the resource.
+func testDestroy(t *testing.T, r *schema.Resource, state *terraform.InstanceState, meta interface{}) {
+	t.Helper()
+	if _, diags := r.Apply(context.Background(), state, &terraform.InstanceDiff{Destroy: true}, meta); diags.HasError() {
+		t.Fatalf("Unable to destroy: %v", diags)
+	}
+}
+
+// testImport imports the resource with the given ID.
+func testImport(t *testing.T, r *schema.Resource, id string, meta interface{}) *terraform.InstanceState {
+	t.Helper()
+	data, err := r.Importer.StateContext(context.Background(), r.Data(&terraform.InstanceState{ID: id}), meta)
+	if err != nil {
+		t.Fatalf("Unable to import %q: %v", id, err)
+	}
+	return testRefresh(t, r, data[0].State(), meta)
+}
+
+// testAttributes returns the attributes of the state as a map from names to
+// values.
+func testAttributes(r *schema.Resource, state *terraform.InstanceState) map[string][]string {
+	attributes := map[string][]string{}
+	for _, attribute := range r.Data(state).Get("attributes").(*schema.Set).List() {
+		for name, value := range attribute.(map[string]interface{}) {
+			attributes[name] = append(attributes[name], value.(string))
+		}
+	}
+	return attributes
+}
+
+// testAttributesConfig turns a map from names to values into the
+// configuration of an attributes set.
+func testAttributesConfig(attributes map[string][]string) []interface{} {
+	config := []interface{}{}
+	for name, values := range attributes {
+		for _, value := range values {
+			config = append(config, map[string]interface{}{name: value})
+		}
+	}
+	return config
+}
@@ -0,0 +1,608 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+)
+
+func resourceLDAPEntries() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceLDAPEntriesCreate,
+		ReadContext:   resourceLDAPEntriesRead,
+		UpdateContext: resourceLDAPEntriesUpdate,
+		DeleteContext: resourceLDAPEntriesDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Read:   schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		CustomizeDiff: resourceLDAPEntriesCustomizeDiff,
+
+		Description: "The `ldap_entries`-resource owns a set of entries under a base DN, given either as `entry` blocks or as inline LDIF. Parents are created before their children and deleted after them. DNs are used as written: the `base_dn` of the provider does not apply.",
+
+		Schema: map[string]*schema.Schema{
+			"base_dn": {
+				Type:        schema.TypeString,
+				Description: "The DN all the entries must be located at or under.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"proxied_authorization": proxiedAuthorizationSchema(),
+			"entry": {
+				Type:         schema.TypeSet,
+				Description:  "The entries owned by the resource; if `ldif` is used instead, these are computed from it.",
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"entry", "ldif"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"dn": {
+							Type:        schema.TypeString,
+							Description: "The Distinguished Name (DN) of the entry.",
+							Required:    true,
+						},
+						"object_classes": {
+							Type:        schema.TypeSet,
+							Description: "The set of classes this entry conforms to (e.g. organizationalUnit, inetOrgPerson).",
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Set:         schema.HashString,
+							Required:    true,
+						},
+						"attributes": {
+							Type:        schema.TypeSet,
+							Description: "The map of attributes of this entry; each attribute can be multi-valued.",
+							Set:         attributeHash,
+							Optional:    true,
+							Elem: &schema.Schema{
+								Type:        schema.TypeMap,
+								Description: "The list of values for a given attribute.",
+								Elem: &schema.Schema{
+									Type:        schema.TypeString,
+									Description: "The individual value for the given attribute.",
+								},
+							},
+						},
+					},
+				},
+			},
+			"ldif": {
+				Type:         schema.TypeString,
+				Description:  "The entries owned by the resource as an LDIF document of content or add records.",
+				Optional:     true,
+				ExactlyOneOf: []string{"entry", "ldif"},
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if _, err := ldifToEntries(v.(string)); err != nil {
+						return nil, []error{fmt.Errorf("%q is not a valid LDIF document: %v", k, err)}
+					}
+					return nil, nil
+				},
+			},
+		},
+	}
+}
+
+// managedEntry is a single entry owned by the ldap_entries resource.
+type managedEntry struct {
+	DN            string
+	ObjectClasses *schema.Set
+	Attributes    *schema.Set
+}
+
+func resourceLDAPEntriesCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	// when the entries are given as LDIF, the entry blocks are derived from
+	// it, so that the plan shows per-entry changes
+	if content, ok := d.GetOk("ldif"); ok && d.NewValueKnown("ldif") {
+		entries, err := ldifToEntries(content.(string))
+		if err != nil {
+			return err
+		}
+		if err := d.SetNew("entry", flattenManagedEntries(entries)); err != nil {
+			return err
+		}
+	}
+
+	if !d.NewValueKnown("base_dn") || !d.NewValueKnown("entry") {
+		return nil
+	}
+	return validateManagedEntries(d.Get("base_dn").(string), expandManagedEntries(d.Get("entry").(*schema.Set)))
+}
+
+func resourceLDAPEntriesCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	baseDN := d.Get("base_dn").(string)
+	entries := expandManagedEntries(d.Get("entry").(*schema.Set))
+
+	if err := validateManagedEntries(baseDN, entries); err != nil {
+		return diag.FromErr(err)
+	}
+
+	debugLog("ldap_entries::create - creating %d entries under %q", len(entries), baseDN)
+
+	txnCtx, endTransaction, err := client.StartTransaction(ctx)
+	if err != nil {
+		return ldapDiagnostics(err, baseDN, nil)
+	}
+
+	// parents first
+	d.SetId(baseDN)
+	sortManagedEntries(entries, false)
+	created := []*managedEntry{}
+	for _, entry := range entries {
+		if err := addManagedEntry(txnCtx, client, entry); err != nil {
+			endTransaction(false)
+			// outside of a transaction, the entries added so far remain and
+			// are kept in state, so that they are managed from now on
+			if _, ok := txnCtx.Value(transactionKey{}).(string); ok || len(created) == 0 {
+				d.SetId("")
+			} else if err := d.Set("entry", flattenManagedEntries(created)); err != nil {
+				return diag.FromErr(err)
+			}
+			return ldapDiagnostics(err, entry.DN, cty.GetAttrPath("entry"))
+		}
+		created = append(created, entry)
+	}
+	if err := endTransaction(true); err != nil {
+		d.SetId("")
+		return ldapDiagnostics(err, baseDN, cty.GetAttrPath("entry"))
+	}
+
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPEntriesRead(ctx, d, meta)
+}
+
+func resourceLDAPEntriesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	baseDN := d.Get("base_dn").(string)
+	entries := expandManagedEntries(d.Get("entry").(*schema.Set))
+
+	debugLog("ldap_entries::read - reading %d entries under %q", len(entries), baseDN)
+
+	// the entries are read with a single search of the subtree
+	request := ldap.NewSearchRequest(
+		baseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectclass=*)",
+		[]string{"*"},
+		nil,
+	)
+	found := map[string]*ldap.Entry{}
+	sr, err := client.Search(ctx, request)
+	if err != nil && !isLDAPResultCode(err, ldap.LDAPResultNoSuchObject) {
+		debugLog("ldap_entries::read - search under %q returned an error %v", baseDN, err)
+		return diag.FromErr(err)
+	}
+	if err == nil {
+		for _, e := range sr.Entries {
+			found[strings.ToLower(e.DN)] = e
+		}
+	}
+
+	read := []*managedEntry{}
+	for _, entry := range entries {
+		e := findEntry(found, entry.DN)
+		if e == nil {
+			warnLog("ldap_entries::read - %q no longer exists in LDAP, removing it from state", entry.DN)
+			continue
+		}
+
+		// passwords are never read back and server-managed attributes only
+		// if they are configured
+		keep := []string{}
+		passwords := &schema.Set{F: attributeHash}
+		for _, attribute := range entry.Attributes.List() {
+			for name := range attribute.(map[string]interface{}) {
+				if containsEqualFold(ldifRedactedAttributes, name) {
+					passwords.Add(attribute)
+				} else {
+					keep = append(keep, name)
+				}
+			}
+		}
+		attributes := ldapEntryToAttributeSet(entry.DN, e, keep)
+		for _, password := range passwords.List() {
+			attributes.Add(password)
+		}
+
+		read = append(read, &managedEntry{
+			DN:            entry.DN,
+			ObjectClasses: schema.NewSet(schema.HashString, stringsToInterfaces(e.GetAttributeValues("objectClass"))),
+			Attributes:    attributes,
+		})
+	}
+
+	if err := d.Set("entry", flattenManagedEntries(read)); err != nil {
+		warnLog("ldap_entries::read - error setting entries for %q : %v", baseDN, err)
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceLDAPEntriesUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	baseDN := d.Get("base_dn").(string)
+
+	if !d.HasChange("entry") {
+		return resourceLDAPEntriesRead(ctx, d, meta)
+	}
+
+	o, n := d.GetChange("entry")
+	oldEntries := indexManagedEntries(expandManagedEntries(o.(*schema.Set)))
+	newEntries := expandManagedEntries(n.(*schema.Set))
+
+	if err := validateManagedEntries(baseDN, newEntries); err != nil {
+		return diag.FromErr(err)
+	}
+
+	debugLog("ldap_entries::update - performing update on entries under %q", baseDN)
+
+	txnCtx, endTransaction, err := client.StartTransaction(ctx)
+	if err != nil {
+		return ldapDiagnostics(err, baseDN, nil)
+	}
+	if diags := updateManagedEntries(txnCtx, client, oldEntries, newEntries); diags.HasError() {
+		endTransaction(false)
+		return diags
+	}
+	if err := endTransaction(true); err != nil {
+		return ldapDiagnostics(err, baseDN, cty.GetAttrPath("entry"))
+	}
+
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPEntriesRead(ctx, d, meta)
+}
+
+// updateManagedEntries adds, modifies and deletes entries, so that the old
+// ones converge on the new ones.
+func updateManagedEntries(ctx context.Context, client *Client, oldEntries map[string]*managedEntry, newEntries []*managedEntry) diag.Diagnostics {
+	// create and modify parents first...
+	sortManagedEntries(newEntries, false)
+	for _, entry := range newEntries {
+		key := strings.ToLower(entry.DN)
+		old, ok := oldEntries[key]
+		delete(oldEntries, key)
+		if !ok {
+			if err := addManagedEntry(ctx, client, entry); err != nil {
+				return ldapDiagnostics(err, entry.DN, cty.GetAttrPath("entry"))
+			}
+			continue
+		}
+
+		modify := ldap.NewModifyRequest(entry.DN, []ldap.Control{})
+		if !old.ObjectClasses.Equal(entry.ObjectClasses) {
+			classes := interfacesToStrings(entry.ObjectClasses.List())
+			debugLog("ldap_entries::update - updating classes of %q, new value: %v", entry.DN, classes)
+			addObjectClassDeltas(modify, interfacesToStrings(old.ObjectClasses.List()), classes)
+		}
+		if err := computeAndAddDeltas(modify, old.Attributes, entry.Attributes, []string{"objectClass"}, []string{}, rdnDeltaStrategy(entry.DN, nil)); err != nil {
+			return diag.FromErr(err)
+		}
+		if len(modify.Changes) > 0 {
+			debugLog("ldap_entries::update - modifying %q", entry.DN)
+			if err := client.Modify(ctx, modify); err != nil {
+				errorLog("ldap_entries::update - error modifying %q: %v", entry.DN, err)
+				return ldapDiagnostics(err, entry.DN, cty.GetAttrPath("entry"))
+			}
+		}
+	}
+
+	// ...and delete the entries that are gone, children first
+	removed := make([]*managedEntry, 0, len(oldEntries))
+	for _, entry := range oldEntries {
+		removed = append(removed, entry)
+	}
+	return deleteManagedEntries(ctx, client, removed)
+}
+
+func resourceLDAPEntriesDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	baseDN := d.Get("base_dn").(string)
+	debugLog("ldap_entries::delete - removing entries under %q", baseDN)
+
+	txnCtx, endTransaction, err := client.StartTransaction(ctx)
+	if err != nil {
+		return ldapDiagnostics(err, baseDN, nil)
+	}
+	if diags := deleteManagedEntries(txnCtx, client, expandManagedEntries(d.Get("entry").(*schema.Set))); diags.HasError() {
+		endTransaction(false)
+		return diags
+	}
+	if err := endTransaction(true); err != nil {
+		return ldapDiagnostics(err, baseDN, nil)
+	}
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return nil
+}
+
+func addManagedEntry(ctx context.Context, client *Client, entry *managedEntry) error {
+	debugLog("ldap_entries::add - adding %q", entry.DN)
+
+	request := ldap.NewAddRequest(entry.DN, []ldap.Control{})
+	request.Attribute("objectClass", interfacesToStrings(entry.ObjectClasses.List()))
+	m := make(map[string][]string)
+	for _, attribute := range entry.Attributes.List() {
+		for name, value := range attribute.(map[string]interface{}) {
+			m[name] = append(m[name], toAttributeValue(name, value.(string)))
+		}
+	}
+	for name, values := range m {
+		request.Attribute(name, values)
+	}
+
+	if err := client.Add(ctx, request); err != nil {
+		errorLog("ldap_entries::add - error adding %q: %v", entry.DN, err)
+		return errors.Wrapf(err, "Adding %q", entry.DN)
+	}
+	return nil
+}
+
+// deleteManagedEntries deletes the given entries, children first; entries
+// that are already gone are ignored.
+func deleteManagedEntries(ctx context.Context, client *Client, entries []*managedEntry) diag.Diagnostics {
+	sortManagedEntries(entries, true)
+	for _, entry := range entries {
+		debugLog("ldap_entries::delete - removing %q", entry.DN)
+		existed, err := deleteExistingEntry(ctx, client, entry.DN)
+		if !existed && err == nil {
+			warnLog("ldap_entries::delete - %q is already gone", entry.DN)
+		}
+		if err != nil {
+			errorLog("ldap_entries::delete - error removing %q: %v", entry.DN, err)
+			return ldapDiagnostics(err, entry.DN, nil)
+		}
+	}
+	return nil
+}
+
+// validateManagedEntries makes sure that all entries are located under the
+// base DN and that no DN is used twice.
+func validateManagedEntries(baseDN string, entries []*managedEntry) error {
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !isDescendantOrSelf(entry.DN, baseDN) {
+			return fmt.Errorf("Entry %q is not located under the base DN %q", entry.DN, baseDN)
+		}
+		key := strings.ToLower(entry.DN)
+		if seen[key] {
+			return fmt.Errorf("Entry %q is defined more than once", entry.DN)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// sortManagedEntries orders entries by the depth of their DN, parents first
+// or, if reverse is set, children first.
+func sortManagedEntries(entries []*managedEntry, reverse bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		di, dj := dnDepth(entries[i].DN), dnDepth(entries[j].DN)
+		if di == dj {
+			return strings.ToLower(entries[i].DN) < strings.ToLower(entries[j].DN)
+		}
+		if reverse {
+			return di > dj
+		}
+		return di < dj
+	})
+}
+
+func indexManagedEntries(entries []*managedEntry) map[string]*managedEntry {
+	index := make(map[string]*managedEntry, len(entries))
+	for _, entry := range entries {
+		index[strings.ToLower(entry.DN)] = entry
+	}
+	return index
+}
+
+func expandManagedEntries(set *schema.Set) []*managedEntry {
+	entries := make([]*managedEntry, 0, set.Len())
+	for _, e := range set.List() {
+		m := e.(map[string]interface{})
+		// the diff of a set of nested sets can hold empty elements for the
+		// removed ones
+		if m["dn"].(string) == "" {
+			continue
+		}
+		entry := &managedEntry{
+			DN:            m["dn"].(string),
+			ObjectClasses: schema.NewSet(schema.HashString, nil),
+			Attributes:    &schema.Set{F: attributeHash},
+		}
+		if classes, ok := m["object_classes"].(*schema.Set); ok {
+			entry.ObjectClasses = classes
+		}
+		if attributes, ok := m["attributes"].(*schema.Set); ok {
+			entry.Attributes = attributes
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func flattenManagedEntries(entries []*managedEntry) []interface{} {
+	blocks := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		blocks = append(blocks, map[string]interface{}{
+			"dn":             entry.DN,
+			"object_classes": entry.ObjectClasses,
+			"attributes":     entry.Attributes,
+		})
+	}
+	return blocks
+}
+
+// ldifToEntries converts an LDIF document of content or add records into the
+// entries it describes; as when reading entries back, the RDN is not treated
+// as an ordinary attribute.
+func ldifToEntries(content string) ([]*managedEntry, error) {
+	records, err := parseLDIF(content)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*managedEntry, 0, len(records))
+	for _, record := range records {
+		if !record.IsAdd() {
+			return nil, fmt.Errorf("LDIF record %q is a %q record, only content and add records are supported", record.DN, record.ChangeType)
+		}
+		entry := &managedEntry{
+			DN:            record.DN,
+			ObjectClasses: schema.NewSet(schema.HashString, nil),
+			Attributes:    &schema.Set{F: attributeHash},
+		}
+		for _, attribute := range record.Attributes {
+			if strings.EqualFold(attribute.Type, "objectClass") {
+				for _, v := range attribute.Vals {
+					entry.ObjectClasses.Add(v)
+				}
+				continue
+			}
+			for _, v := range attribute.Vals {
+				if isRDNValue(record.DN, attribute.Type, v) {
+					continue
+				}
+				entry.Attributes.Add(map[string]interface{}{attribute.Type: v})
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ldapEntryToAttributeSet converts the attributes of an entry into the set of
+// single-valued maps used by the resources, leaving out objectClass, the RDN
+// and, unless they are to be kept, passwords and server-managed attributes.
+func ldapEntryToAttributeSet(dn string, entry *ldap.Entry, keep []string) *schema.Set {
+	set := &schema.Set{F: attributeHash}
+	objectClasses := entry.GetAttributeValues("objectClass")
+	for _, attribute := range entry.Attributes {
+		if strings.EqualFold(attribute.Name, "objectClass") {
+			continue
+		}
+		if (containsEqualFold(ldifRedactedAttributes, attribute.Name) || isServerManagedAttribute(attribute.Name, objectClasses)) &&
+			!containsEqualFold(keep, attribute.Name) {
+			continue
+		}
+		for _, value := range attribute.Values {
+			if isRDNValue(dn, attribute.Name, value) {
+				continue
+			}
+			set.Add(map[string]interface{}{attribute.Name: value})
+		}
+	}
+	return set
+}
+
+// isRDNValue checks whether the value of the attribute is a naming value of
+// the RDN of dn, which is not treated as an ordinary value.
+func isRDNValue(dn, name, value string) bool {
+	d, err := ldap.ParseDN(dn)
+	if err != nil || len(d.RDNs) == 0 {
+		return false
+	}
+	for _, a := range d.RDNs[0].Attributes {
+		if strings.EqualFold(a.Type, name) && strings.EqualFold(a.Value, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// rdnDeltaStrategy makes the naming attributes of dn change by deltas, which
+// never touch the naming values the attribute sets leave out.
+func rdnDeltaStrategy(dn string, strategy func(name string) string) func(name string) string {
+	return func(name string) string {
+		d, err := ldap.ParseDN(dn)
+		if err == nil && len(d.RDNs) > 0 {
+			for _, a := range d.RDNs[0].Attributes {
+				if strings.EqualFold(a.Type, name) {
+					return strategyDelta
+				}
+			}
+		}
+		if strategy == nil {
+			return strategyAuto
+		}
+		return strategy(name)
+	}
+}
+
+// isDescendantOrSelf checks whether dn is equal to or located under base.
+func isDescendantOrSelf(dn, base string) bool {
+	d, err := ldap.ParseDN(dn)
+	if err != nil {
+		return false
+	}
+	b, err := ldap.ParseDN(base)
+	if err != nil {
+		return false
+	}
+	return b.Equal(d) || b.AncestorOf(d)
+}
+
+// findEntry looks up the entry with the DN among the entries indexed by their
+// lowercase DN, comparing the DNs as such if there is no exact match.
+func findEntry(entries map[string]*ldap.Entry, dn string) *ldap.Entry {
+	if entry, ok := entries[strings.ToLower(dn)]; ok {
+		return entry
+	}
+	for _, entry := range entries {
+		if isSameDN(entry.DN, dn) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// isSameDN checks whether both DNs name the same entry.
+func isSameDN(a, b string) bool {
+	return isDescendantOrSelf(a, b) && dnDepth(a) == dnDepth(b)
+}
+
+// dnDepth returns the number of RDNs in the DN.
+func dnDepth(dn string) int {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.Count(dn, ",") + 1
+	}
+	return len(parsed.RDNs)
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result
+}
+
+func interfacesToStrings(values []interface{}) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		result = append(result, v.(string))
+	}
+	return result
+}
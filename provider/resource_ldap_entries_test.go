@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// testEntriesConfig returns the configuration of an ldap_entries resource
+// owning organizational units with the given DNs.
+func testEntriesConfig(classes []interface{}, dns ...string) map[string]interface{} {
+	entries := []interface{}{}
+	for _, dn := range dns {
+		entries = append(entries, map[string]interface{}{
+			"dn":             dn,
+			"object_classes": classes,
+			"attributes":     testAttributesConfig(map[string][]string{"description": {dn}}),
+		})
+	}
+	return map[string]interface{}{
+		"base_dn": testBaseDN,
+		"entry":   entries,
+	}
+}
+
+func TestLDAPEntriesLifecycle(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPEntries()
+	people, staff, groups := "ou=people,"+testBaseDN, "ou=staff,ou=people,"+testBaseDN, "ou=groups,"+testBaseDN
+	classes := []interface{}{"organizationalUnit"}
+
+	config := testEntriesConfig(classes, staff, people)
+	state := testApply(t, r, nil, config, meta)
+	if s.Entry(people) == nil || s.Entry(staff) == nil {
+		t.Fatalf("Expected the entries to be added")
+	}
+	if !testPlanIsEmpty(t, r, testRefresh(t, r, state, meta), config, meta) {
+		t.Errorf("Expected no changes after creating the entries")
+	}
+
+	// classes are added and removed one by one, entries added and deleted
+	var ldif strings.Builder
+	meta.(*Client).SetLDIFOutput(&ldif, false)
+	config = testEntriesConfig([]interface{}{"organizationalUnit", "extensibleObject"}, people, groups)
+	state = testApply(t, r, state, config, meta)
+	if !strings.Contains(ldif.String(), "add: objectClass\nobjectClass: extensibleObject\n-\n") || strings.Contains(ldif.String(), "replace: objectClass") {
+		t.Errorf("Expected the class to be added on its own, got:\n%s", ldif.String())
+	}
+	if values := s.Values(people, "objectClass"); !testSameValues(values, []string{"organizationalUnit", "extensibleObject"}) {
+		t.Errorf("Unexpected classes %v", values)
+	}
+	if s.Entry(groups) == nil || s.Entry(staff) != nil {
+		t.Errorf("Expected %q to be added and %q to be deleted", groups, staff)
+	}
+	if !testPlanIsEmpty(t, r, testRefresh(t, r, state, meta), config, meta) {
+		t.Errorf("Expected no changes after updating the entries")
+	}
+
+	testDestroy(t, r, state, meta)
+	if s.Entry(people) != nil || s.Entry(groups) != nil {
+		t.Errorf("Expected the entries to be deleted")
+	}
+}
+
+func TestLDAPEntriesPartialFailure(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPEntries()
+	people, missing := "ou=people,"+testBaseDN, "ou=staff,ou=missing,"+testBaseDN
+
+	// without a transaction, the entries added before the failure are kept
+	// in state
+	diff, err := r.Diff(ctx, nil, terraform.NewResourceConfigRaw(testEntriesConfig([]interface{}{"organizationalUnit"}, people, missing)), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	state, diags := r.Apply(ctx, nil, diff, meta)
+	if !diags.HasError() {
+		t.Fatalf("Expected adding %q to fail", missing)
+	}
+	if state == nil || state.ID == "" {
+		t.Fatalf("Expected the added entries to be kept in state")
+	}
+	entries := expandManagedEntries(r.Data(state).Get("entry").(*schema.Set))
+	if len(entries) != 1 || entries[0].DN != people {
+		t.Errorf("Expected only %q in state, got %v", people, entries)
+	}
+
+	// and thus deleted along with the resource
+	testDestroy(t, r, state, meta)
+	if s.Entry(people) != nil {
+		t.Errorf("Expected %q to be deleted", people)
+	}
+}
+
+func TestLDAPEntriesRDNValues(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPEntries()
+	people := "OU=People," + testBaseDN
+
+	// only the naming value is left out of a multi-valued naming attribute,
+	// whatever its case
+	config := map[string]interface{}{
+		"base_dn": testBaseDN,
+		"entry": []interface{}{map[string]interface{}{
+			"dn":             people,
+			"object_classes": []interface{}{"organizationalUnit"},
+			"attributes":     testAttributesConfig(map[string][]string{"ou": {"staff"}}),
+		}},
+	}
+	state := testApply(t, r, nil, config, meta)
+	s.SetAttribute(people, "ou", "people", "staff")
+	state = testRefresh(t, r, state, meta)
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes with the naming value left out")
+	}
+
+	// dropping the other values keeps the naming value
+	config["entry"].([]interface{})[0].(map[string]interface{})["attributes"] = testAttributesConfig(map[string][]string{"description": {"people"}})
+	testApply(t, r, state, config, meta)
+	if values := s.Values(people, "ou"); !testSameValues(values, []string{"people"}) {
+		t.Errorf("Expected only the naming value to be kept, got %v", values)
+	}
+}
+
+func TestLDAPEntriesServerManagedAttributesAndPasswords(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPEntries()
+	jdoe := "cn=jdoe," + testBaseDN
+
+	config := map[string]interface{}{
+		"base_dn": testBaseDN,
+		"entry": []interface{}{map[string]interface{}{
+			"dn":             jdoe,
+			"object_classes": []interface{}{"inetOrgPerson"},
+			"attributes":     testAttributesConfig(map[string][]string{"sn": {"Doe"}, "userPassword": {"secret"}}),
+		}},
+	}
+	state := testApply(t, r, nil, config, meta)
+
+	// attributes the server maintains and hashed passwords are no drift
+	s.SetAttribute(jdoe, "memberOf", "cn=admins,"+testBaseDN)
+	s.SetAttribute(jdoe, "userPassword", "{SSHA}c2VjcmV0")
+	state = testRefresh(t, r, state, meta)
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes for server-managed attributes and hashed passwords")
+	}
+}
@@ -0,0 +1,335 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+)
+
+func resourceLDAPLDIF() *schema.Resource {
+	// the resource can't be updated, so the identity can't either
+	proxiedAuthorization := proxiedAuthorizationSchema()
+	proxiedAuthorization.ForceNew = true
+
+	return &schema.Resource{
+		CreateContext: resourceLDAPLDIFCreate,
+		ReadContext:   resourceLDAPLDIFRead,
+		DeleteContext: resourceLDAPLDIFDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Read:   schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Description: "The `ldap_ldif`-resource applies the content and change records of an LDIF document. Entries created by content and add records are tracked and deleted again on destroy; all other changes are applied once and not reversed. The DNs of the document are used as written: the `base_dn` of the provider does not apply.",
+
+		Schema: map[string]*schema.Schema{
+			"content": {
+				Type:        schema.TypeString,
+				Description: "The LDIF document (RFC 2849) to apply; records are applied in order.",
+				Required:    true,
+				ForceNew:    true,
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if _, err := parseLDIF(v.(string)); err != nil {
+						return nil, []error{fmt.Errorf("%q is not a valid LDIF document: %v", k, err)}
+					}
+					return nil, nil
+				},
+			},
+			"entries": {
+				Type:        schema.TypeList,
+				Description: "The DNs of the entries created by content and add records, in order; entries renamed or deleted by later records are followed.",
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"adopt_existing": {
+				Type:        schema.TypeBool,
+				Description: "Whether content and add records whose entry exists already replace the attributes of the record on it instead of failing; adopted entries are not tracked and thus not deleted on destroy. Such documents are never applied as transactions.",
+				Optional:    true,
+				ForceNew:    true,
+				Default:     false,
+			},
+			"proxied_authorization": proxiedAuthorization,
+		},
+	}
+}
+
+func resourceLDAPLDIFCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	content := d.Get("content").(string)
+
+	records, err := parseLDIF(content)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	debugLog("ldap_ldif::create - applying %d records", len(records))
+
+	// within a transaction, errors may only be reported when committing it,
+	// too late to adopt existing entries: documents adopting them are not
+	// applied as transactions
+	txnCtx, endTransaction := ctx, func(bool) error { return nil }
+	if !d.Get("adopt_existing").(bool) {
+		if txnCtx, endTransaction, err = client.StartTransaction(ctx); err != nil {
+			return ldapDiagnostics(err, "", nil)
+		}
+	}
+
+	entries := []string{}
+	for _, record := range records {
+		debugLog("ldap_ldif::create - applying %q record for %q", record.ChangeType, record.DN)
+		err := applyLDIFRecord(txnCtx, client, record)
+		adopted := false
+		if err != nil && record.IsAdd() && d.Get("adopt_existing").(bool) && isLDAPResultCode(err, ldap.LDAPResultEntryAlreadyExists) {
+			warnLog("ldap_ldif::create - %q already exists, replacing the attributes of the record", record.DN)
+			modify := ldap.NewModifyRequest(record.DN, []ldap.Control{})
+			for _, attribute := range record.Attributes {
+				modify.Replace(attribute.Type, attribute.Vals)
+			}
+			err = client.Modify(txnCtx, modify)
+			adopted = true
+		}
+		if err != nil {
+			errorLog("ldap_ldif::create - error applying record for %q: %v", record.DN, err)
+			endTransaction(false)
+			return ldapDiagnostics(err, record.DN, cty.GetAttrPath("content"))
+		}
+		entries = trackLDIFEntries(entries, record, adopted)
+	}
+	if err := endTransaction(true); err != nil {
+		return ldapDiagnostics(err, "", cty.GetAttrPath("content"))
+	}
+
+	d.SetId(fmt.Sprintf("%x", sha256.Sum256([]byte(content))))
+	if err := d.Set("entries", entries); err != nil {
+		return diag.FromErr(err)
+	}
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPLDIFRead(ctx, d, meta)
+}
+
+func resourceLDAPLDIFRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+
+	records, err := parseLDIF(d.Get("content").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// only content and add records whose entries no later record changes
+	// describe a state that can be checked for drift
+	for _, record := range finalLDIFRecords(records) {
+		drifted, err := ldifRecordDrifted(ctx, client, record)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if !drifted {
+			continue
+		}
+		for _, other := range records {
+			if !other.IsAdd() {
+				// change records may not be applied twice, so the document
+				// is only applied again on request
+				warnLog("ldap_ldif::read - %q drifted from its record", record.DN)
+				return diag.Diagnostics{{
+					Severity: diag.Warning,
+					Summary:  fmt.Sprintf("%q drifted from its LDIF record", record.DN),
+					Detail:   "The document holds change records, which may not be applied twice, so it is not applied again automatically; replace the resource to do so.",
+				}}
+			}
+		}
+		// a content differing from the configuration makes Terraform replace
+		// the resource: its entries are deleted and the document is applied
+		// again
+		warnLog("ldap_ldif::read - %q drifted from its record, replacing the resource", record.DN)
+		if err := d.Set("content", ""); err != nil {
+			return diag.FromErr(err)
+		}
+		return nil
+	}
+	return nil
+}
+
+func resourceLDAPLDIFDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+
+	txnCtx, endTransaction, err := client.StartTransaction(ctx)
+	if err != nil {
+		return ldapDiagnostics(err, "", nil)
+	}
+
+	// remove the created entries in reverse order, so that children are
+	// removed before their parents
+	entries := d.Get("entries").([]interface{})
+	for i := len(entries) - 1; i >= 0; i-- {
+		dn := entries[i].(string)
+		debugLog("ldap_ldif::delete - removing %q", dn)
+		existed, err := deleteExistingEntry(txnCtx, client, dn)
+		if !existed && err == nil {
+			warnLog("ldap_ldif::delete - %q is already gone", dn)
+		}
+		if err != nil {
+			errorLog("ldap_ldif::delete - error removing %q: %v", dn, err)
+			endTransaction(false)
+			return ldapDiagnostics(err, dn, nil)
+		}
+	}
+	if err := endTransaction(true); err != nil {
+		return ldapDiagnostics(err, "", nil)
+	}
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return nil
+}
+
+// trackLDIFEntries updates the DNs of the entries created by a document after
+// the record was applied: the entry of an add record is added unless it was
+// adopted, and entries deleted or moved by the record are removed or renamed.
+func trackLDIFEntries(entries []string, record *ldifRecord, adopted bool) []string {
+	switch record.ChangeType {
+	case "", ldifChangeTypeAdd:
+		if !adopted {
+			entries = append(entries, record.DN)
+		}
+	case ldifChangeTypeDelete:
+		remaining := []string{}
+		for _, dn := range entries {
+			if !isSameDN(dn, record.DN) {
+				remaining = append(remaining, dn)
+			}
+		}
+		entries = remaining
+	case ldifChangeTypeModRDN, ldifChangeTypeModDN:
+		for i, dn := range entries {
+			entries[i] = movedLDIFDN(dn, record)
+		}
+	}
+	return entries
+}
+
+// movedLDIFDN returns the DN an entry has after the modrdn or moddn record was
+// applied, which differs if it is the moved entry or one of its descendants.
+func movedLDIFDN(dn string, record *ldifRecord) string {
+	if !isDescendantOrSelf(dn, record.DN) {
+		return dn
+	}
+	_, parent := splitDN(record.DN)
+	if record.NewSuperior != "" {
+		parent = record.NewSuperior
+	}
+	moved := record.NewRDN
+	if parent != "" {
+		moved += "," + parent
+	}
+	if dnDepth(dn) > dnDepth(record.DN) {
+		moved = relativeDN(dn, record.DN) + "," + moved
+	}
+	return moved
+}
+
+// finalLDIFRecords returns the content and add records whose entries are
+// neither changed, moved nor deleted by a later record of the document, so
+// that they describe the final state of their entries.
+func finalLDIFRecords(records []*ldifRecord) []*ldifRecord {
+	final := []*ldifRecord{}
+	for i, record := range records {
+		if !record.IsAdd() {
+			continue
+		}
+		changed := false
+		for _, later := range records[i+1:] {
+			switch later.ChangeType {
+			case ldifChangeTypeModify, ldifChangeTypeDelete:
+				changed = changed || isSameDN(record.DN, later.DN)
+			case ldifChangeTypeModRDN, ldifChangeTypeModDN:
+				changed = changed || isDescendantOrSelf(record.DN, later.DN)
+			}
+		}
+		if !changed {
+			final = append(final, record)
+		}
+	}
+	return final
+}
+
+// ldifRecordDrifted checks whether the entry created by a content or add
+// record is missing or lacks any of the values in the record; passwords are
+// not compared, as servers return them hashed or not at all.
+func ldifRecordDrifted(ctx context.Context, client *Client, record *ldifRecord) (bool, error) {
+	names := make([]string, 0, len(record.Attributes))
+	for _, attribute := range record.Attributes {
+		if !containsEqualFold(ldifRedactedAttributes, attribute.Type) {
+			names = append(names, attribute.Type)
+		}
+	}
+
+	request := ldap.NewSearchRequest(
+		record.DN,
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectClass=*)",
+		names,
+		nil,
+	)
+
+	sr, err := client.Search(ctx, request)
+	if err != nil {
+		if isLDAPResultCode(err, ldap.LDAPResultNoSuchObject) {
+			debugLog("ldap_ldif::read - %q not found", record.DN)
+			return true, nil
+		}
+		debugLog("ldap_ldif::read - lookup for %q returned an error %v", record.DN, err)
+		return false, err
+	}
+	if len(sr.Entries) == 0 {
+		debugLog("ldap_ldif::read - %q not found", record.DN)
+		return true, nil
+	}
+
+	for _, attribute := range record.Attributes {
+		if containsEqualFold(ldifRedactedAttributes, attribute.Type) {
+			continue
+		}
+		actual := sr.Entries[0].GetEqualFoldAttributeValues(attribute.Type)
+		for _, v := range attribute.Vals {
+			if !containsEqualFold(actual, v) {
+				debugLog("ldap_ldif::read - %q is missing value %q of %q", record.DN, v, attribute.Type)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// isLDAPResultCode checks whether err is, or wraps, an LDAP error with the
+// given result code.
+func isLDAPResultCode(err error, code uint16) bool {
+	var e *ldap.Error
+	return errors.As(err, &e) && e.ResultCode == code
+}
+
+func containsEqualFold(haystack []string, needle string) bool {
+	for _, h := range haystack {
+		if strings.EqualFold(h, needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/pkg/errors"
+)
+
+func TestLDAPLDIFLifecycle(t *testing.T) {
+	r := resourceLDAPLDIF()
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	people, alice, bob := "ou=people,"+testBaseDN, "cn=alice,ou=people,"+testBaseDN, "cn=bob,ou=people,"+testBaseDN
+
+	content := `dn: ` + people + `
+objectClass: organizationalUnit
+ou: people
+
+dn: ` + alice + `
+objectClass: person
+cn: alice
+sn: Alice
+
+dn: ` + alice + `
+changetype: modrdn
+newrdn: cn=bob
+deleteoldrdn: 1
+`
+	config := map[string]interface{}{"content": content}
+	state := testApply(t, r, nil, config, meta)
+	if e := r.Data(state).Get("entries"); !reflect.DeepEqual(e, []interface{}{people, bob}) {
+		t.Errorf("Expected the renamed entry to be tracked, got %v", e)
+	}
+	// alice is renamed by a later record, so its record is not checked
+	state = testRefresh(t, r, state, meta)
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes after renaming an added entry")
+	}
+
+	// a drifted change record is reported, but not applied again
+	s.SetAttribute(people, "ou", "staff")
+	newState, diags := r.RefreshWithoutUpgrade(context.Background(), state, meta)
+	if diags.HasError() || len(diags) != 1 {
+		t.Fatalf("Expected a warning about the drift, got %v", diags)
+	}
+	if !testPlanIsEmpty(t, r, newState, config, meta) {
+		t.Errorf("Expected a document with change records not to be applied again")
+	}
+	testDestroy(t, r, newState, meta)
+	if s.Entry(people) != nil || s.Entry(bob) != nil {
+		t.Errorf("Expected the created entries to be deleted")
+	}
+
+	// a document of content records is applied again after drifting
+	content = `dn: ` + people + `
+objectClass: organizationalUnit
+ou: people
+`
+	config = map[string]interface{}{"content": content}
+	state = testApply(t, r, nil, config, meta)
+	s.DeleteEntry(people)
+	state = testRefresh(t, r, state, meta)
+	if testPlanIsEmpty(t, r, state, config, meta) {
+		t.Fatalf("Expected the drifted document to be applied again")
+	}
+	state = testApply(t, r, state, config, meta)
+	if s.Entry(people) == nil {
+		t.Errorf("Expected %q to be added again", people)
+	}
+
+	// existing entries are only adopted on request, and never deleted
+	ctx := context.Background()
+	diff, err := r.Diff(ctx, nil, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	if _, diags := r.Apply(ctx, nil, diff, meta); !diags.HasError() {
+		t.Errorf("Expected adding the existing %q to fail", people)
+	}
+	adopting := testApply(t, r, nil, map[string]interface{}{"content": content, "adopt_existing": true}, meta)
+	if e := r.Data(adopting).Get("entries").([]interface{}); len(e) != 0 {
+		t.Errorf("Expected the adopted entry not to be tracked, got %v", e)
+	}
+	testDestroy(t, r, adopting, meta)
+	if s.Entry(people) == nil {
+		t.Errorf("Expected the adopted %q not to be deleted", people)
+	}
+	testDestroy(t, r, state, meta)
+}
+
+func TestLDAPLDIFPasswordsNotCompared(t *testing.T) {
+	r := resourceLDAPLDIF()
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	alice := "cn=alice," + testBaseDN
+
+	config := map[string]interface{}{"content": `dn: ` + alice + `
+objectClass: person
+cn: alice
+sn: Alice
+userPassword: secret
+`}
+	state := testApply(t, r, nil, config, meta)
+
+	// servers return passwords hashed, which isn't drift
+	s.SetAttribute(alice, "userPassword", "{SSHA}c2VjcmV0")
+	state = testRefresh(t, r, state, meta)
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected a hashed password not to be reported as drift")
+	}
+}
+
+func TestIsLDAPResultCode(t *testing.T) {
+	err := ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("no such object"))
+	for _, wrapped := range []error{err, errors.Wrap(err, "Deleting"), fmt.Errorf("deleting: %w", err)} {
+		if !isLDAPResultCode(wrapped, ldap.LDAPResultNoSuchObject) {
+			t.Errorf("Expected %v to have result code 32", wrapped)
+		}
+	}
+	if isLDAPResultCode(err, ldap.LDAPResultEntryAlreadyExists) || isLDAPResultCode(fmt.Errorf("other"), ldap.LDAPResultNoSuchObject) {
+		t.Errorf("Expected other result codes and errors not to match")
+	}
+}
@@ -7,23 +7,34 @@ import (
 	"context"
 	"fmt"
 	"hash/crc32"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/trevex/terraform-provider-ldap/util"
 
 	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"golang.org/x/text/encoding/unicode"
 )
 
 func resourceLDAPObject() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceLDAPObjectCreate,
-		Read:   resourceLDAPObjectRead,
-		Update: resourceLDAPObjectUpdate,
-		Delete: resourceLDAPObjectDelete,
-		Exists: resourceLDAPObjectExists,
+		CreateContext: resourceLDAPObjectCreate,
+		ReadContext:   resourceLDAPObjectRead,
+		UpdateContext: resourceLDAPObjectUpdate,
+		DeleteContext: resourceLDAPObjectDelete,
+		CustomizeDiff: resourceLDAPObjectCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Read:   schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceLDAPObjectImport,
@@ -61,6 +72,8 @@ func resourceLDAPObject() *schema.Resource {
 				},
 				Optional: true,
 			},
+			"sensitive_attributes": sensitiveAttributesSchema(),
+			"attribute_policy":     attributePolicySchema(),
 			"skip_attributes": {
 				Type:        schema.TypeSet,
 				Description: "A list of attributes which will not be tracked by the provider",
@@ -75,59 +88,123 @@ func resourceLDAPObject() *schema.Resource {
 				Set:         schema.HashString,
 				Optional:    true,
 			},
+			"computed_attributes": {
+				Type:        schema.TypeSet,
+				Description: "A list of operational or server-generated attributes (e.g. entryUUID, createTimestamp, objectGUID) to read into computed_attributes_json, or \"+\" for all operational ones; they are never managed by the provider.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Set:         schema.HashString,
+				Optional:    true,
+			},
+			"computed_attributes_json": {
+				Type:        schema.TypeMap,
+				Description: "A map of the JSON-encoded values of the computed attributes. Each entry is a JSON encoded string list; GUIDs and SIDs are in their string forms, other binary values are base64-encoded.",
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type:        schema.TypeString,
+					Description: "A json-encoded array of strings",
+				},
+			},
+			"recreate_on_structural_change": {
+				Type:        schema.TypeBool,
+				Description: "If a change of object_classes changes the structural class of the object, which servers don't allow, recreate the object instead of failing at plan time.",
+				Optional:    true,
+				Default:     false,
+			},
+			"modify_strategy": {
+				Type:         schema.TypeString,
+				Description:  "How attributes whose values change are modified: replace sends all their values, delta only adds and deletes the values that changed, leaving values added concurrently alone, and auto (the default) uses delta for attributes with many values; it can be set per attribute with attribute_policy.",
+				Optional:     true,
+				Default:      strategyAuto,
+				ValidateFunc: validation.StringInSlice([]string{strategyAuto, strategyReplace, strategyDelta}, false),
+			},
+			"assert_prior_values": {
+				Type:        schema.TypeBool,
+				Description: "Make updates conditional on the modified attributes still holding the values in the state (RFC 4528 assertion control), so that changes made by other means since the plan fail the update instead of being overwritten. Sensitive attributes, attributes without an equality matching rule and binary values are not checked, and neither are values added since the plan: with the replace strategy, these are overwritten.",
+				Optional:    true,
+				Default:     false,
+			},
+			"permissive_modify":     permissiveModifySchema(),
+			"relax_rules":           relaxRulesSchema(),
+			"proxied_authorization": proxiedAuthorizationSchema(),
+			"adopt_existing": {
+				Type:        schema.TypeBool,
+				Description: "If the object exists already when it is created, adopt it instead of failing: its classes and attributes are modified to match the configuration and from then on it is managed (and eventually deleted) by the provider.",
+				Optional:    true,
+				Default:     false,
+			},
 		},
 	}
 }
 
+// The import ID is either the DN of the object or the DN followed by the
+// attributes to skip and/or select, e.g.
+// "cn=jdoe,dc=example,dc=com|skip=userPassword,description|select=cn,sn".
 func resourceLDAPObjectImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
-	dn := d.Id()
-	debugLog("Going to import dn %q", dn)
+	ctx = withProxiedAuthorization(ctx, d)
+	dn, skip, selected, err := parseLDAPObjectImportID(d.Id())
+	if err != nil {
+		return nil, err
+	}
+	debugLog("Going to import dn %q (skipping %v, selecting %v)", dn, skip, selected)
+	client := meta.(*Client)
+	// the DN is kept as given, as the configuration is expected to match it
 	d.Set("dn", dn)
-	err := resourceLDAPObjectRead(d, meta)
+	d.SetId(client.ExpandDN(dn))
+	// the defaults are not set on import
+	for name, s := range resourceLDAPObject().Schema {
+		if s.Default != nil {
+			d.Set(name, s.Default)
+		}
+	}
+	if len(skip) > 0 {
+		d.Set("skip_attributes", skip)
+	}
+	if len(selected) > 0 {
+		d.Set("select_attributes", selected)
+	}
+	err = readLDAPObject(ctx, d, meta, true)
 	return []*schema.ResourceData{d}, errors.Wrap(err, "Reading ldap object")
 }
 
-func resourceLDAPObjectExists(d *schema.ResourceData, meta interface{}) (b bool, e error) {
-	l := meta.(*ldap.Conn)
-	dn := d.Get("dn").(string)
-
-	debugLog("ldap_object::exists - checking if %q exists", dn)
-
-	// search by primary key (that is, set the DN as base DN and use a "base
-	// object" scope); no attributes are retrieved since we are onòy checking
-	// for existence; all objects have an "objectClass" attribute, so the filter
-	// is a "match all"
-	request := ldap.NewSearchRequest(
-		dn,
-		ldap.ScopeBaseObject,
-		ldap.NeverDerefAliases,
-		0,
-		0,
-		false,
-		"(objectClass=*)",
-		nil,
-		nil,
-	)
-
-	_, err := l.Search(request)
-	if err != nil {
-		if err, ok := err.(*ldap.Error); ok {
-			if err.ResultCode == 32 { // no such object
-				warnLog("ldap_object::exists - lookup for %q returned no value: deleted on server?", dn)
-				return false, nil
+// parseLDAPObjectImportID splits an import ID into the DN and the lists of
+// attributes to skip and to select.
+func parseLDAPObjectImportID(id string) (string, []string, []string, error) {
+	parts := strings.Split(id, "|")
+	dn := strings.TrimSpace(parts[0])
+	if dn == "" {
+		return "", nil, nil, fmt.Errorf("Invalid import ID %q: missing DN", id)
+	}
+	skip := []string{}
+	selected := []string{}
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, nil, fmt.Errorf("Invalid import ID %q: expected skip=... or select=..., got %q", id, part)
+		}
+		names := []string{}
+		for _, name := range strings.Split(kv[1], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
 			}
 		}
-		debugLog("ldap_object::exists - lookup for %q returned an error %v", dn, err)
-		return false, err
+		switch strings.TrimSpace(kv[0]) {
+		case "skip":
+			skip = append(skip, names...)
+		case "select":
+			selected = append(selected, names...)
+		default:
+			return "", nil, nil, fmt.Errorf("Invalid import ID %q: unknown option %q", id, kv[0])
+		}
 	}
-
-	debugLog("ldap_object::exists - object %q exists", dn)
-	return true, nil
+	return dn, skip, selected, nil
 }
 
-func resourceLDAPObjectCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*ldap.Conn)
-	dn := d.Get("dn").(string)
+func resourceLDAPObjectCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	ctx = withWriteOptions(ctx, d)
+	ctx = withSensitiveAttributes(ctx, d)
+	dn := client.ExpandDN(d.Get("dn").(string))
 
 	debugLog("ldap_object::create - creating a new object under %q", dn)
 
@@ -142,7 +219,7 @@ func resourceLDAPObjectCreate(d *schema.ResourceData, meta interface{}) error {
 	request.Attribute("objectClass", objectClasses)
 
 	// retrieve attributes to skip from HCL
-	attributesToSkip := []string{"objectClass"}
+	attributesToSkip := append([]string{"objectClass"}, client.DefaultSkipAttributes()...)
 	for _, attr := range (d.Get("skip_attributes").(*schema.Set)).List() {
 		debugLog("ldap_object::create - object %q set to skip: %q", dn, attr.(string))
 		attributesToSkip = append(attributesToSkip, attr.(string))
@@ -155,6 +232,10 @@ func resourceLDAPObjectCreate(d *schema.ResourceData, meta interface{}) error {
 		attributesToSet = append(attributesToSet, attr.(string))
 	}
 
+	// sensitive attributes are handled on their own
+	sensitive := d.Get("sensitive_attributes").(*schema.Set)
+	attributesToSkip = append(attributesToSkip, attributeSetNames(sensitive)...)
+
 	// if there is a non empty list of attributes, loop though it and
 	// create a new map collecting attribute names and its value(s); we need to
 	// do this because we could not model the attributes as a map[string][]string
@@ -188,16 +269,137 @@ func resourceLDAPObjectCreate(d *schema.ResourceData, meta interface{}) error {
 			}
 		}
 	}
+	for name, values := range attributeSetValues(sensitive) {
+		debugLog("ldap_object::create - %q has sensitive attribute %q", dn, name)
+		request.Attribute(name, values)
+	}
 
-	err := client.Add(request)
+	err := client.Add(ctx, request)
+	if err != nil && d.Get("adopt_existing").(bool) && isLDAPResultCode(err, ldap.LDAPResultEntryAlreadyExists) {
+		warnLog("ldap_object::create - object %q already exists, adopting it", dn)
+		err = adoptLDAPObject(ctx, d, client, attributesToSkip, attributesToSet)
+	}
 	if err != nil {
-		return err
+		return ldapDiagnostics(err, dn, cty.GetAttrPath("attributes"))
 	}
 
 	debugLog("ldap_object::create - object %q added to LDAP server", dn)
 
 	d.SetId(dn)
-	return resourceLDAPObjectRead(d, meta)
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPObjectRead(ctx, d, meta)
+}
+
+// adoptLDAPObject reads an existing object and modifies it, so that its
+// classes and attributes converge on the configuration.
+func adoptLDAPObject(ctx context.Context, d *schema.ResourceData, client *Client, attributesToSkip, attributesToSet []string) error {
+	dn := client.ExpandDN(d.Get("dn").(string))
+
+	entry, err := lookupLDAPObject(ctx, client, dn)
+	if err != nil {
+		debugLog("ldap_object::adopt - lookup for %q returned an error %v", dn, err)
+		return err
+	}
+
+	modify := ldap.NewModifyRequest(dn, []ldap.Control{})
+	policies := attributePolicies(d.Get("attribute_policy").(*schema.Set))
+
+	classes := interfacesToStrings(d.Get("object_classes").(*schema.Set).List())
+	if attributePolicy(policies, "objectClass") == policyAdditive {
+		addAdditiveDeltas(modify, entry, "objectClass", nil, classes)
+	} else {
+		addObjectClassDeltas(modify, entry.GetAttributeValues("objectClass"), classes)
+	}
+
+	// additive attributes keep the values they already have
+	attributes := d.Get("attributes").(*schema.Set)
+	additive := attributeNamesWithPolicy(attributes, policies, policyAdditive)
+	configured := attributeSetValues(attributes)
+	for _, name := range additive {
+		if !shouldSkipAttribute(name, attributesToSkip, attributesToSet) {
+			addAdditiveDeltas(modify, entry, name, nil, configured[name])
+		}
+	}
+
+	// like on read, server-managed attributes are left alone unless they are
+	// configured, and so are passwords and the attributes no object tracks
+	keep := append([]string{}, attributesToSet...)
+	for name := range configured {
+		keep = append(keep, name)
+	}
+	existing := &schema.Set{F: attributeHash}
+	for _, attribute := range ldapEntryToAttributeSet(dn, entry, keep).List() {
+		for name := range attribute.(map[string]interface{}) {
+			if !containsEqualFold(client.DefaultSkipAttributes(), name) {
+				existing.Add(attribute)
+			}
+		}
+	}
+	debugLog("ldap_object::adopt - \n%s", printAttributes("existing attributes map", existing))
+	err = computeAndAddDeltas(modify, existing, attributes, append(attributesToSkip, additive...), attributesToSet, rdnDeltaStrategy(dn, modifyStrategies(d)))
+	if err != nil {
+		return err
+	}
+	// the current values of sensitive attributes are unknown, so they are
+	// written anyway
+	addSensitiveAttributeDeltas(modify, &schema.Set{F: attributeHash}, d.Get("sensitive_attributes").(*schema.Set))
+
+	if len(modify.Changes) == 0 {
+		debugLog("ldap_object::adopt - %q already matches the configuration", dn)
+		return nil
+	}
+	if err := client.Modify(ctx, modify); err != nil {
+		errorLog("ldap_object::adopt - error modifying LDAP object %q with values %v", dn, err)
+		return err
+	}
+	debugLog("ldap_object::adopt - object %q adopted", dn)
+	return nil
+}
+
+// addObjectClassDeltas adds and deletes individual object classes, rather
+// than replacing all of them, which would touch the structural class.
+func addObjectClassDeltas(modify *ldap.ModifyRequest, oldClasses, newClasses []string) {
+	added := []string{}
+	for _, oc := range newClasses {
+		if !containsEqualFold(oldClasses, oc) {
+			added = append(added, oc)
+		}
+	}
+	removed := []string{}
+	for _, oc := range oldClasses {
+		if !containsEqualFold(newClasses, oc) {
+			removed = append(removed, oc)
+		}
+	}
+	if len(added) > 0 {
+		debugLog("ldap_object::deltas - adding classes %v", added)
+		modify.Add("objectClass", added)
+	}
+	if len(removed) > 0 {
+		debugLog("ldap_object::deltas - removing classes %v", removed)
+		modify.Delete("objectClass", removed)
+	}
+}
+
+// equalFoldSets checks whether both slices hold the same values, ignoring
+// their case and order.
+func equalFoldSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, v := range a {
+		if !containsEqualFold(b, v) {
+			return false
+		}
+	}
+	for _, v := range b {
+		if !containsEqualFold(a, v) {
+			return false
+		}
+	}
+	return true
 }
 
 func stringSliceContains(haystack []string, needle string) bool {
@@ -209,18 +411,71 @@ func stringSliceContains(haystack []string, needle string) bool {
 	return false
 }
 
-func resourceLDAPObjectRead(d *schema.ResourceData, meta interface{}) error {
-	return readLDAPObject(d, meta, true)
+// resourceLDAPObjectCustomizeDiff validates the configuration as a whole and
+// checks whether changes of the classes of an existing object change its
+// structural class, which can only be done by recreating it.
+func resourceLDAPObjectCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	policies := d.Get("attribute_policy").(*schema.Set)
+	if err := validateAttributePolicies(policies); err != nil {
+		return err
+	}
+	if d.Id() == "" || !d.HasChange("object_classes") || !d.NewValueKnown("object_classes") ||
+		attributePolicy(attributePolicies(policies), "objectClass") == policyCreateOnly {
+		return nil
+	}
+
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	o, n := d.GetChange("object_classes")
+	oldClasses := interfacesToStrings(o.(*schema.Set).List())
+	newClasses := interfacesToStrings(n.(*schema.Set).List())
+
+	s, err := client.Schema(ctx)
+	if err != nil {
+		warnLog("ldap_object::plan - unable to read the schema, the structural class of %q is not checked: %v", d.Id(), err)
+		return nil
+	}
+
+	oldStructural, newStructural := structuralObjectClasses(s, oldClasses), structuralObjectClasses(s, newClasses)
+	if equalFoldSets(oldStructural, newStructural) {
+		return nil
+	}
+	if d.Get("recreate_on_structural_change").(bool) {
+		debugLog("ldap_object::plan - the structural class of %q changes from %v to %v, recreating it", d.Id(), oldStructural, newStructural)
+		return d.ForceNew("object_classes")
+	}
+	return fmt.Errorf("Changing the structural object classes of %q from %v to %v requires recreating it, set recreate_on_structural_change to do so", d.Id(), oldStructural, newStructural)
 }
 
-func resourceLDAPObjectUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*ldap.Conn)
+// structuralObjectClasses returns the classes that are structural according
+// to the schema; classes it doesn't define are left out.
+func structuralObjectClasses(s *util.Schema, classes []string) []string {
+	structural := []string{}
+	for _, oc := range classes {
+		// object classes are structural unless declared otherwise
+		if definition := s.ObjectClass(oc); definition != nil && (definition.Kind == "" || definition.Kind == "STRUCTURAL") {
+			structural = append(structural, oc)
+		}
+	}
+	return structural
+}
+
+func resourceLDAPObjectRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	return diag.FromErr(readLDAPObject(ctx, d, meta, true))
+}
+
+func resourceLDAPObjectUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	ctx = withWriteOptions(ctx, d)
+	ctx = withSensitiveAttributes(ctx, d)
 
 	dn := d.Id()
 	debugLog("ldap_object::update - performing update on %q", dn)
 
 	// retrieve attributes to skip from HCL
-	attributesToSkip := []string{"objectClass"}
+	attributesToSkip := append([]string{"objectClass"}, client.DefaultSkipAttributes()...)
 	for _, attr := range (d.Get("skip_attributes").(*schema.Set)).List() {
 		debugLog("ldap_object::create - object %q set to skip: %q", dn, attr.(string))
 		attributesToSkip = append(attributesToSkip, attr.(string))
@@ -233,16 +488,42 @@ func resourceLDAPObjectUpdate(d *schema.ResourceData, meta interface{}) error {
 		attributesToSet = append(attributesToSet, attr.(string))
 	}
 
+	// sensitive attributes are handled on their own
+	os, ns := d.GetChange("sensitive_attributes")
+	attributesToSkip = append(attributesToSkip, attributeSetNames(os.(*schema.Set))...)
+	attributesToSkip = append(attributesToSkip, attributeSetNames(ns.(*schema.Set))...)
+
 	modify := ldap.NewModifyRequest(dn, []ldap.Control{})
+	policies := attributePolicies(d.Get("attribute_policy").(*schema.Set))
+
+	// additive attributes are changed against the current values of the
+	// object, so it is only looked up if there are any
+	var entry *ldap.Entry
+	lookup := func() error {
+		if entry != nil {
+			return nil
+		}
+		var err error
+		entry, err = lookupLDAPObject(ctx, client, dn)
+		return err
+	}
 
 	// handle objectClasses
 	if d.HasChange("object_classes") {
-		classes := []string{}
-		for _, oc := range (d.Get("object_classes").(*schema.Set)).List() {
-			classes = append(classes, oc.(string))
+		o, n := d.GetChange("object_classes")
+		classes := interfacesToStrings(n.(*schema.Set).List())
+		switch attributePolicy(policies, "objectClass") {
+		case policyCreateOnly:
+			debugLog("ldap_object::update - ignoring changed classes of %q", dn)
+		case policyAdditive:
+			if err := lookup(); err != nil {
+				return ldapDiagnostics(err, dn, nil)
+			}
+			addAdditiveDeltas(modify, entry, "objectClass", interfacesToStrings(o.(*schema.Set).List()), classes)
+		default:
+			debugLog("ldap_object::update - updating classes of %q, new value: %v", d.Id(), classes)
+			addObjectClassDeltas(modify, interfacesToStrings(o.(*schema.Set).List()), classes)
 		}
-		debugLog("ldap_object::update - updating classes of %q, new value: %v", d.Id(), classes)
-		modify.Replace("objectClass", classes)
 	}
 
 	if d.HasChange("attributes") {
@@ -250,44 +531,127 @@ func resourceLDAPObjectUpdate(d *schema.ResourceData, meta interface{}) error {
 		debugLog("ldap_object::update - \n%s", printAttributes("old attributes map", o))
 		debugLog("ldap_object::update - \n%s", printAttributes("new attributes map", n))
 
-		err := computeAndAddDeltas(modify, o.(*schema.Set), n.(*schema.Set), attributesToSkip, attributesToSet)
+		// additive attributes are handled on their own, create-only ones are
+		// left alone
+		oldValues, newValues := attributeSetValues(o.(*schema.Set)), attributeSetValues(n.(*schema.Set))
+		additive := []string{}
+		for _, set := range []*schema.Set{o.(*schema.Set), n.(*schema.Set)} {
+			for _, name := range attributeNamesWithPolicy(set, policies, policyAdditive) {
+				if !stringSliceContains(additive, name) {
+					additive = append(additive, name)
+				}
+			}
+			attributesToSkip = append(attributesToSkip, attributeNamesWithPolicy(set, policies, policyCreateOnly)...)
+		}
+		sort.Strings(additive)
+		for _, name := range additive {
+			if equalStringSets(oldValues[name], newValues[name]) || shouldSkipAttribute(name, attributesToSkip, attributesToSet) {
+				continue
+			}
+			if err := lookup(); err != nil {
+				return ldapDiagnostics(err, dn, nil)
+			}
+			addAdditiveDeltas(modify, entry, name, oldValues[name], newValues[name])
+		}
+		attributesToSkip = append(attributesToSkip, additive...)
+
+		err := computeAndAddDeltas(modify, o.(*schema.Set), n.(*schema.Set), attributesToSkip, attributesToSet, rdnDeltaStrategy(dn, modifyStrategies(d)))
 		if err != nil {
-			return err
+			return diag.FromErr(err)
+		}
+	}
+
+	if d.HasChange("sensitive_attributes") {
+		addSensitiveAttributeDeltas(modify, os.(*schema.Set), ns.(*schema.Set))
+	}
+
+	if len(modify.Changes) > 0 && d.Get("assert_prior_values").(bool) {
+		oc, _ := d.GetChange("object_classes")
+		oa, _ := d.GetChange("attributes")
+		priorValues := attributeSetValues(oa.(*schema.Set))
+		sensitive := append(attributeSetNames(os.(*schema.Set)), attributeSetNames(ns.(*schema.Set))...)
+		// attributes without an equality rule cannot be asserted
+		s, err := client.Schema(ctx)
+		if err != nil {
+			warnLog("ldap_object::update - unable to read the schema, all modified attributes of %q are asserted: %v", dn, err)
+		}
+		filter := assertionFilter(modify, s, func(name string) ([]string, bool) {
+			if strings.EqualFold(name, "objectClass") {
+				return interfacesToStrings(oc.(*schema.Set).List()), true
+			}
+			if containsEqualFold(sensitive, name) {
+				return nil, false
+			}
+			values := []string{}
+			for n, v := range priorValues {
+				if strings.EqualFold(n, name) {
+					values = append(values, v...)
+				}
+			}
+			// additive attributes may have values that are not tracked
+			return values, len(values) > 0 || attributePolicy(policies, name) != policyAdditive
+		})
+		if filter != "" {
+			debugLog("ldap_object::update - asserting %s on %q", filter, dn)
+			control, err := assertionControl(filter)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			modify.Controls = append(modify.Controls, control)
 		}
 	}
 
 	if len(modify.Changes) > 0 {
-		err := client.Modify(modify)
+		err := client.Modify(ctx, modify)
 		if err != nil {
 			errorLog("ldap_object::update - error modifying LDAP object %q with values %v", d.Id(), err)
-			return err
+			return ldapDiagnostics(err, dn, cty.GetAttrPath("attributes"))
 		}
 	} else {
 		warnLog("ldap_object::update - didn't actually make changes to %q because there were no changes requested", dn)
 	}
-	return resourceLDAPObjectRead(d, meta)
+
+	// create-only values are never sent once the object exists, so the state
+	// keeps the ones it was created with and plans keep showing the change
+	if attributePolicy(policies, "objectClass") == policyCreateOnly && d.HasChange("object_classes") {
+		o, _ := d.GetChange("object_classes")
+		d.Set("object_classes", o)
+	}
+	if d.HasChange("attributes") {
+		o, n := d.GetChange("attributes")
+		d.Set("attributes", keepCreateOnlyValues(o.(*schema.Set), n.(*schema.Set), policies))
+	}
+
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPObjectRead(ctx, d, meta)
 }
 
-func resourceLDAPObjectDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*ldap.Conn)
-	dn := d.Get("dn").(string)
+func resourceLDAPObjectDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	dn := client.ExpandDN(d.Get("dn").(string))
 
 	debugLog("ldap_object::delete - removing %q", dn)
 
 	request := ldap.NewDelRequest(dn, nil)
 
-	err := client.Del(request)
+	err := client.Del(ctx, request)
 	if err != nil {
 		errorLog("ldap_object::delete - error removing %q: %v", dn, err)
-		return err
+		return ldapDiagnostics(err, dn, nil)
+	}
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
 	}
 	debugLog("ldap_object::delete - %q removed", dn)
 	return nil
 }
 
-func readLDAPObject(d *schema.ResourceData, meta interface{}, updateState bool) error {
-	client := meta.(*ldap.Conn)
-	dn := d.Get("dn").(string)
+func readLDAPObject(ctx context.Context, d *schema.ResourceData, meta interface{}, updateState bool) error {
+	client := meta.(*Client)
+	dn := client.ExpandDN(d.Get("dn").(string))
 
 	debugLog("ldap_object::read - looking for object %q", dn)
 
@@ -307,7 +671,7 @@ func readLDAPObject(d *schema.ResourceData, meta interface{}, updateState bool)
 		nil,
 	)
 
-	sr, err := client.Search(request)
+	sr, err := client.Search(ctx, request)
 	if err != nil {
 		if err, ok := err.(*ldap.Error); ok {
 			if err.ResultCode == 32 && updateState { // no such object
@@ -323,10 +687,31 @@ func readLDAPObject(d *schema.ResourceData, meta interface{}, updateState bool)
 	debugLog("ldap_object::read - query for %q returned %v", dn, sr)
 
 	d.SetId(dn)
-	d.Set("object_classes", sr.Entries[0].GetAttributeValues("objectClass"))
+
+	policies := attributePolicies(d.Get("attribute_policy").(*schema.Set))
+	classes := sr.Entries[0].GetAttributeValues("objectClass")
+	tracked := interfacesToStrings(d.Get("object_classes").(*schema.Set).List())
+	switch attributePolicy(policies, "objectClass") {
+	case policyCreateOnly:
+		// the classes in state are kept, unless there are none yet
+		if len(tracked) == 0 {
+			d.Set("object_classes", classes)
+		}
+	case policyAdditive:
+		// only the classes owned by the provider are tracked
+		owned := []string{}
+		for _, oc := range tracked {
+			if containsEqualFold(classes, oc) {
+				owned = append(owned, oc)
+			}
+		}
+		d.Set("object_classes", owned)
+	default:
+		d.Set("object_classes", classes)
+	}
 
 	// retrieve attributes to skip from HCL
-	attributesToSkip := []string{"objectClass"}
+	attributesToSkip := append([]string{"objectClass"}, client.DefaultSkipAttributes()...)
 	for _, attr := range (d.Get("skip_attributes").(*schema.Set)).List() {
 		debugLog("ldap_object::create - object %q set to skip: %q", dn, attr.(string))
 		attributesToSkip = append(attributesToSkip, attr.(string))
@@ -339,24 +724,61 @@ func readLDAPObject(d *schema.ResourceData, meta interface{}, updateState bool)
 		attributesToSet = append(attributesToSet, attr.(string))
 	}
 
+	// sensitive attributes are never read back
+	attributesToSkip = append(attributesToSkip, attributeSetNames(d.Get("sensitive_attributes").(*schema.Set))...)
+
+	// computed attributes are kept apart
+	computedAttributes := []string{}
+	for _, attr := range (d.Get("computed_attributes").(*schema.Set)).List() {
+		computedAttributes = append(computedAttributes, attr.(string))
+	}
+	attributesToSkip = append(attributesToSkip, computedAttributes...)
+
+	// the attributes tracked so far; operational and server-generated
+	// attributes are left out unless they are tracked or explicitly selected
+	tracked = []string{}
+	trackedValues := map[string][]string{}
+	for _, attribute := range d.Get("attributes").(*schema.Set).List() {
+		for name, value := range attribute.(map[string]interface{}) {
+			tracked = append(tracked, name)
+			trackedValues[name] = append(trackedValues[name], value.(string))
+		}
+	}
+	objectClasses := sr.Entries[0].GetAttributeValues("objectClass")
+
 	// now deal with attributes
 	set := &schema.Set{
 		F: attributeHash,
 	}
 
 	for _, attribute := range sr.Entries[0].Attributes {
-		debugLog("ldap_object::read - treating attribute %q of %q (%d values: %v)", attribute.Name, dn, len(attribute.Values), attribute.Values)
+		// skipped attributes include the sensitive ones, whose values must
+		// not be logged
 		if shouldSkipAttribute(attribute.Name, attributesToSkip, attributesToSet) {
 			debugLog("ldap_object::read - skipping attribute %q for %q", attribute.Name, dn)
 			continue
 		}
-		if len(attribute.Values) == 1 {
-			// we don't treat the RDN as an ordinary attribute
-			a := fmt.Sprintf("%s=%s", attribute.Name, attribute.Values[0])
-			if strings.HasPrefix(dn, a) {
-				debugLog("ldap_object::read - skipping RDN %q of %q", a, dn)
+		debugLog("ldap_object::read - treating attribute %q of %q (%d values: %v)", attribute.Name, dn, len(attribute.Values), attribute.Values)
+		switch attributePolicy(policies, attribute.Name) {
+		case policyCreateOnly:
+			if len(trackedValues[attribute.Name]) > 0 {
+				// the values in state are kept, see below
 				continue
 			}
+		case policyAdditive:
+			// only the values owned by the provider are tracked
+			for _, value := range attribute.Values {
+				if stringSliceContains(trackedValues[attribute.Name], value) {
+					set.Add(map[string]interface{}{
+						attribute.Name: value,
+					})
+				}
+			}
+			continue
+		}
+		if isServerManagedAttribute(attribute.Name, objectClasses) && !containsEqualFold(tracked, attribute.Name) && !containsEqualFold(attributesToSet, attribute.Name) {
+			debugLog("ldap_object::read - skipping server-managed attribute %q for %q", attribute.Name, dn)
+			continue
 		}
 		debugLog("ldap_object::read - adding attribute %q to %q (%d values)", attribute.Name, dn, len(attribute.Values))
 		// now add each value as an individual entry into the object, because
@@ -364,6 +786,11 @@ func readLDAPObject(d *schema.ResourceData, meta interface{}, updateState bool)
 		// holding a single entry name => value; multiple maps may share the
 		// same key.
 		for _, value := range attribute.Values {
+			// we don't treat the RDN as an ordinary value
+			if isRDNValue(dn, attribute.Name, value) {
+				debugLog("ldap_object::read - skipping RDN %s=%s of %q", attribute.Name, value, dn)
+				continue
+			}
 			debugLog("ldap_object::read - for %q, setting %q => %q", dn, attribute.Name, value)
 			set.Add(map[string]interface{}{
 				attribute.Name: value,
@@ -371,10 +798,33 @@ func readLDAPObject(d *schema.ResourceData, meta interface{}, updateState bool)
 		}
 	}
 
+	for name, values := range trackedValues {
+		if attributePolicy(policies, name) == policyCreateOnly && !shouldSkipAttribute(name, attributesToSkip, attributesToSet) {
+			debugLog("ldap_object::read - keeping create-only attribute %q of %q", name, dn)
+			for _, value := range values {
+				set.Add(map[string]interface{}{
+					name: value,
+				})
+			}
+		}
+	}
+
 	if err := d.Set("attributes", set); err != nil {
 		warnLog("ldap_object::read - error setting LDAP attributes for %q : %v", dn, err)
 		return err
 	}
+
+	computed := map[string]string{}
+	if len(computedAttributes) > 0 {
+		debugLog("ldap_object::read - looking for computed attributes %v of %q", computedAttributes, dn)
+		if computed, err = readComputedAttributes(ctx, client, dn, computedAttributes); err != nil {
+			return err
+		}
+	}
+	if err := d.Set("computed_attributes_json", computed); err != nil {
+		warnLog("ldap_object::read - error setting computed attributes for %q : %v", dn, err)
+		return err
+	}
 	return nil
 }
 
@@ -425,7 +875,7 @@ func shouldSkipAttribute(k string, attributesToSkip, attributesToSet []string) b
 	return false
 }
 
-func computeAndAddDeltas(modify *ldap.ModifyRequest, os, ns *schema.Set, attributesToSkip, attributesToSet []string) error {
+func computeAndAddDeltas(modify *ldap.ModifyRequest, os, ns *schema.Set, attributesToSkip, attributesToSet []string, strategy func(name string) string) error {
 	rk := util.NewSet() // names of removed attributes
 	for _, v := range os.Difference(ns).List() {
 		for k := range v.(map[string]interface{}) {
@@ -460,7 +910,11 @@ func computeAndAddDeltas(modify *ldap.ModifyRequest, os, ns *schema.Set, attribu
 			// name among those that were untouched; this means that it has
 			// been dropped and must go among the RemovedAttributes
 			debugLog("ldap_object::deltas - dropping attribute %q", k)
-			modify.Delete(k, []string{})
+			if oldValues := attributeSetValues(os)[k]; useModifyDeltas(strategy, k, oldValues, nil) {
+				modify.Delete(k, oldValues)
+			} else {
+				modify.Delete(k, []string{})
+			}
 		} else {
 			ck.Add(k)
 		}
@@ -509,6 +963,20 @@ func computeAndAddDeltas(modify *ldap.ModifyRequest, os, ns *schema.Set, attribu
 				}
 			}
 		}
+		oldValues := []string{}
+		for _, m := range os.List() {
+			for mk, mv := range m.(map[string]interface{}) {
+				if k == mk {
+					oldValues = append(oldValues, toAttributeValue(k, mv.(string)))
+				}
+			}
+		}
+		if useModifyDeltas(strategy, k, oldValues, values) {
+			// only the values that changed are sent, leaving the others (and
+			// those added concurrently) alone
+			addValueDeltas(modify, k, oldValues, values)
+			continue
+		}
 		modify.Replace(k, values)
 		debugLog("ldap_object::deltas - changing attribute %q with values %v", k, values)
 	}
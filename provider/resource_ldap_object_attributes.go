@@ -1,22 +1,41 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
 	"github.com/trevex/terraform-provider-ldap/util"
 )
 
 func resourceLDAPObjectAttributes() *schema.Resource {
+	// like the other attributes, sensitive ones are owned value by value
+	sensitive := sensitiveAttributesSchema()
+	sensitive.Description = "The map of sensitive attributes to add to the referenced object, e.g. passwords; their values are hidden in plans and, as they are usually write-only or hashed by the server, never read back, so they don't cause drift."
+
 	return &schema.Resource{
-		Create: resourceLDAPObjectAttributesCreate,
-		Read:   resourceLDAPObjectAttributesRead,
-		Update: resourceLDAPObjectAttributesUpdate,
-		Delete: resourceLDAPObjectAttributesDelete,
+		CreateContext: resourceLDAPObjectAttributesCreate,
+		ReadContext:   resourceLDAPObjectAttributesRead,
+		UpdateContext: resourceLDAPObjectAttributesUpdate,
+		DeleteContext: resourceLDAPObjectAttributesDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceLDAPObjectAttributesImport,
+		},
 
-		Description: "The `ldap_object_attributes`-resource owns only specific attributes of an object. In case of multi-valued attributes the resource only owns the values defined by the resource and all pre-existing ones or ones added by other means are left in-tact.",
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Read:   schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Description: "The `ldap_object_attributes`-resource owns only specific attributes of an object. In case of multi-valued attributes the resource only owns the values defined by the resource and all pre-existing ones or ones added by other means are left in-tact. The same holds for sensitive attributes, whose values are removed when the resource is destroyed.",
 
 		Schema: map[string]*schema.Schema{
 			"dn": {
@@ -43,13 +62,121 @@ func resourceLDAPObjectAttributes() *schema.Resource {
 				},
 				Optional: true,
 			},
+			"sensitive_attributes":  sensitive,
+			"permissive_modify":     permissiveModifySchema(),
+			"relax_rules":           relaxRulesSchema(),
+			"proxied_authorization": proxiedAuthorizationSchema(),
 		},
 	}
 }
 
-func resourceLDAPObjectAttributesCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*ldap.Conn)
-	dn := d.Get("dn").(string)
+// The import ID is the DN of the object followed by the attributes to own,
+// either as a list of names whose values are all owned, or as a single value
+// of an attribute, e.g. "cn=admins,dc=example,dc=com|description,mail" or
+// "cn=admins,dc=example,dc=com|member=cn=jdoe,dc=example,dc=com"; several
+// parts can be separated by "|".
+func resourceLDAPObjectAttributesImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+
+	dn, names, values, err := parseLDAPObjectAttributesImportID(d.Id())
+	if err != nil {
+		return nil, err
+	}
+	// the DN is kept as given, as the configuration is expected to match it
+	d.Set("dn", dn)
+	dn = client.ExpandDN(dn)
+	debugLog("ldap_object_attributes::import - importing attributes %v and values %v of %q", names, values, dn)
+
+	set := &schema.Set{
+		F: attributeHash,
+	}
+	for _, value := range values {
+		set.Add(value)
+	}
+	if len(names) > 0 {
+		request := ldap.NewSearchRequest(
+			dn,
+			ldap.ScopeBaseObject,
+			ldap.NeverDerefAliases,
+			0,
+			0,
+			false,
+			"(objectclass=*)",
+			names,
+			nil,
+		)
+		sr, err := client.Search(ctx, request)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Reading %q", dn)
+		}
+		for _, attribute := range sr.Entries[0].Attributes {
+			if !containsEqualFold(names, attribute.Name) {
+				continue
+			}
+			for _, value := range attribute.Values {
+				set.Add(map[string]interface{}{
+					attribute.Name: value,
+				})
+			}
+		}
+	}
+
+	d.SetId(dn)
+	d.Set("permissive_modify", false)
+	d.Set("relax_rules", false)
+	if err := d.Set("attributes", set); err != nil {
+		return nil, err
+	}
+	if diags := resourceLDAPObjectAttributesRead(ctx, d, meta); diags.HasError() {
+		return nil, fmt.Errorf("Reading attributes of %q: %s", dn, diags[0].Summary)
+	}
+	if d.Id() == "" {
+		return nil, fmt.Errorf("None of the attributes to import exist on %q", dn)
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// parseLDAPObjectAttributesImportID splits an import ID into the DN, the
+// names of the attributes whose values are all imported, and the single
+// attribute values to import.
+func parseLDAPObjectAttributesImportID(id string) (string, []string, []map[string]interface{}, error) {
+	parts := strings.Split(id, "|")
+	dn := strings.TrimSpace(parts[0])
+	if dn == "" || len(parts) < 2 {
+		return "", nil, nil, fmt.Errorf("Invalid import ID %q: expected <dn>|attr1,attr2 or <dn>|attr=value", id)
+	}
+	names := []string{}
+	values := []map[string]interface{}{}
+	for _, part := range parts[1:] {
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 {
+			name := strings.TrimSpace(kv[0])
+			if name == "" {
+				return "", nil, nil, fmt.Errorf("Invalid import ID %q: missing attribute name in %q", id, part)
+			}
+			values = append(values, map[string]interface{}{
+				name: kv[1],
+			})
+			continue
+		}
+		for _, name := range strings.Split(part, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 && len(values) == 0 {
+		return "", nil, nil, fmt.Errorf("Invalid import ID %q: no attributes given", id)
+	}
+	return dn, names, values, nil
+}
+
+func resourceLDAPObjectAttributesCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	ctx = withWriteOptions(ctx, d)
+	ctx = withSensitiveAttributes(ctx, d)
+	dn := client.ExpandDN(d.Get("dn").(string))
 
 	debugLog("ldap_object_attributes::create - adding attributes to object %q", dn)
 
@@ -81,20 +208,25 @@ func resourceLDAPObjectAttributesCreate(d *schema.ResourceData, meta interface{}
 			}
 		}
 	}
+	addSensitiveValueDeltas(request, &schema.Set{F: attributeHash}, d.Get("sensitive_attributes").(*schema.Set))
 
-	err := client.Modify(request)
+	err := client.Modify(ctx, request)
 	if err != nil {
-		return err
+		return ldapDiagnostics(err, dn, cty.GetAttrPath("attributes"))
 	}
 
 	debugLog("ldap_object_attributes::create - object %q updated with additional attributes", dn)
 
-	return resourceLDAPObjectAttributesRead(d, meta)
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPObjectAttributesRead(ctx, d, meta)
 }
 
-func resourceLDAPObjectAttributesRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*ldap.Conn)
-	dn := d.Get("dn").(string)
+func resourceLDAPObjectAttributesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	dn := client.ExpandDN(d.Get("dn").(string))
 
 	debugLog("ldap_object_attributes::read - looking for object %q", dn)
 
@@ -114,7 +246,7 @@ func resourceLDAPObjectAttributesRead(d *schema.ResourceData, meta interface{})
 		nil,
 	)
 
-	sr, err := client.Search(request)
+	sr, err := client.Search(ctx, request)
 	if err != nil {
 		if err, ok := err.(*ldap.Error); ok {
 			if err.ResultCode == 32 { // no such object
@@ -123,7 +255,7 @@ func resourceLDAPObjectAttributesRead(d *schema.ResourceData, meta interface{})
 			}
 		}
 		debugLog("ldap_object_attributes::read - lookup for %q returned an error %v", dn, err)
-		return err
+		return diag.FromErr(err)
 	}
 
 	debugLog("ldap_object_attributes::read - query for %q returned %v", dn, sr)
@@ -184,8 +316,9 @@ func resourceLDAPObjectAttributesRead(d *schema.ResourceData, meta interface{})
 	set := unionSet.Intersection(ldapSet)
 	debugLog("ldap_object_attributes::read - intersection with ldap of %q => %v", dn, set.List())
 
-	// If the set is empty the attributes do not exist, yet.
-	if set.Len() == 0 {
+	// If the set is empty the attributes do not exist, yet; sensitive
+	// attributes can't be read back, so they are assumed to exist.
+	if set.Len() == 0 && d.Get("sensitive_attributes").(*schema.Set).Len() == 0 {
 		d.SetId("")
 		return nil
 	}
@@ -194,15 +327,18 @@ func resourceLDAPObjectAttributesRead(d *schema.ResourceData, meta interface{})
 	// exists by setting the id as well.
 	if err := d.Set("attributes", set); err != nil {
 		warnLog("ldap_object_attributes::read - error setting attributes for %q : %v", dn, err)
-		return err
+		return diag.FromErr(err)
 	}
 	d.SetId(dn)
 	return nil
 }
 
-func resourceLDAPObjectAttributesUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*ldap.Conn)
-	dn := d.Get("dn").(string)
+func resourceLDAPObjectAttributesUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	ctx = withWriteOptions(ctx, d)
+	ctx = withSensitiveAttributes(ctx, d)
+	dn := client.ExpandDN(d.Get("dn").(string))
 
 	debugLog("ldap_object_attributes::update - performing update on %q", dn)
 
@@ -215,25 +351,36 @@ func resourceLDAPObjectAttributesUpdate(d *schema.ResourceData, meta interface{}
 
 		err := computeAndAddAttributeDeltas(modify, o.(*schema.Set), n.(*schema.Set))
 		if err != nil {
-			return err
+			return diag.FromErr(err)
 		}
 	}
 
+	if d.HasChange("sensitive_attributes") {
+		o, n := d.GetChange("sensitive_attributes")
+		addSensitiveValueDeltas(modify, o.(*schema.Set), n.(*schema.Set))
+	}
+
 	if len(modify.Changes) > 0 {
-		err := client.Modify(modify)
+		err := client.Modify(ctx, modify)
 		if err != nil {
 			errorLog("ldap_object_attributes::update - error modifying LDAP object %q with values %v", d.Id(), err)
-			return err
+			return ldapDiagnostics(err, dn, cty.GetAttrPath("attributes"))
 		}
 	} else {
 		warnLog("ldap_object_attributes::update - didn't actually make changes to %q because there were no changes requested", dn)
 	}
-	return resourceLDAPObjectAttributesRead(d, meta)
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPObjectAttributesRead(ctx, d, meta)
 }
 
-func resourceLDAPObjectAttributesDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*ldap.Conn)
-	dn := d.Get("dn").(string)
+func resourceLDAPObjectAttributesDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	ctx = withProxiedAuthorization(ctx, d)
+	client := meta.(*Client)
+	ctx = withWriteOptions(ctx, d)
+	ctx = withSensitiveAttributes(ctx, d)
+	dn := client.ExpandDN(d.Get("dn").(string))
 
 	debugLog("ldap_object_attributes::delete - removing attributes from %q", dn)
 
@@ -243,18 +390,23 @@ func resourceLDAPObjectAttributesDelete(d *schema.ResourceData, meta interface{}
 		F: attributeHash,
 	})
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
+	// the values of the sensitive attributes are removed as well
+	addSensitiveValueDeltas(modify, d.Get("sensitive_attributes").(*schema.Set), &schema.Set{F: attributeHash})
 
 	if len(modify.Changes) > 0 {
-		err := client.Modify(modify)
+		err := client.Modify(ctx, modify)
 		if err != nil {
 			errorLog("ldap_object_attributes::delete - error modifying LDAP object %q with values %v", d.Id(), err)
-			return err
+			return ldapDiagnostics(err, dn, cty.GetAttrPath("attributes"))
 		}
 	} else {
 		warnLog("ldap_object_attributes::delete - didn't actually make changes to %q because there were no changes requested", dn)
 	}
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
 
 	debugLog("ldap_object::delete - %q removed", dn)
 	return nil
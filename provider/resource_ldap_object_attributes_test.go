@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestParseLDAPObjectAttributesImportID(t *testing.T) {
+	dn, names, values, err := parseLDAPObjectAttributesImportID("cn=admins,dc=example,dc=com|description, mail")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dn != "cn=admins,dc=example,dc=com" || !reflect.DeepEqual(names, []string{"description", "mail"}) || len(values) != 0 {
+		t.Errorf("Unexpected result %q %v %v", dn, names, values)
+	}
+
+	dn, names, values, err = parseLDAPObjectAttributesImportID("cn=admins,dc=example,dc=com|member=cn=jdoe,dc=example,dc=com|member=cn=asmith,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []map[string]interface{}{
+		{"member": "cn=jdoe,dc=example,dc=com"},
+		{"member": "cn=asmith,dc=example,dc=com"},
+	}
+	if dn != "cn=admins,dc=example,dc=com" || len(names) != 0 || !reflect.DeepEqual(values, expected) {
+		t.Errorf("Unexpected result %q %v %v", dn, names, values)
+	}
+
+	for _, id := range []string{"cn=admins,dc=example,dc=com", "cn=admins,dc=example,dc=com|", "|mail", "cn=admins,dc=example,dc=com|=value"} {
+		if _, _, _, err := parseLDAPObjectAttributesImportID(id); err == nil {
+			t.Errorf("Expected an error for %q", id)
+		}
+	}
+}
+
+func TestLDAPObjectAttributesLifecycle(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObjectAttributes()
+	dn := "cn=admins," + testBaseDN
+	s.PutEntry(dn, map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {"admins"},
+		"member":      {"cn=root," + testBaseDN},
+	})
+
+	config := map[string]interface{}{
+		"dn": dn,
+		"attributes": testAttributesConfig(map[string][]string{
+			"member":      {"cn=jdoe," + testBaseDN},
+			"description": {"the admins"},
+		}),
+	}
+
+	// create only adds the owned values
+	state := testApply(t, r, nil, config, meta)
+	if values := s.Values(dn, "member"); !testSameValues(values, []string{"cn=root," + testBaseDN, "cn=jdoe," + testBaseDN}) {
+		t.Errorf("Unexpected member values %v", values)
+	}
+
+	// values added by other means are not part of the state
+	s.SetAttribute(dn, "member", "cn=root,"+testBaseDN, "cn=jdoe,"+testBaseDN, "cn=asmith,"+testBaseDN)
+	state = testRefresh(t, r, state, meta)
+	if values := testAttributes(r, state)["member"]; !testSameValues(values, []string{"cn=jdoe," + testBaseDN}) {
+		t.Errorf("Expected only owned values in state, got %v", values)
+	}
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes for values owned by others")
+	}
+
+	// owned values removed by other means are added back
+	s.SetAttribute(dn, "description")
+	state = testRefresh(t, r, state, meta)
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "description"); !testSameValues(values, []string{"the admins"}) {
+		t.Errorf("Expected description to be added back, got %v", values)
+	}
+
+	// update
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"member": {"cn=jdoe," + testBaseDN, "cn=bob," + testBaseDN},
+	})
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "member"); !testSameValues(values, []string{"cn=root," + testBaseDN, "cn=jdoe," + testBaseDN, "cn=asmith," + testBaseDN, "cn=bob," + testBaseDN}) {
+		t.Errorf("Unexpected member values %v", values)
+	}
+	if values := s.Values(dn, "description"); len(values) != 0 {
+		t.Errorf("Expected description to be removed, got %v", values)
+	}
+
+	// import
+	imported := testImport(t, r, dn+"|member=cn=bob,"+testBaseDN, meta)
+	if values := testAttributes(r, imported)["member"]; !testSameValues(values, []string{"cn=bob," + testBaseDN}) {
+		t.Errorf("Unexpected imported values %v", values)
+	}
+	imported = testImport(t, r, dn+"|member", meta)
+	if values := testAttributes(r, imported)["member"]; len(values) != 4 {
+		t.Errorf("Expected all values to be imported, got %v", values)
+	}
+
+	// destroy only removes the owned values
+	testDestroy(t, r, state, meta)
+	if values := s.Values(dn, "member"); !testSameValues(values, []string{"cn=root," + testBaseDN, "cn=asmith," + testBaseDN}) {
+		t.Errorf("Unexpected member values %v", values)
+	}
+}
+
+func TestLDAPObjectAttributesSensitiveAttributes(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObjectAttributes()
+	dn := "cn=jdoe," + testBaseDN
+	s.PutEntry(dn, map[string][]string{"objectClass": {"inetOrgPerson"}, "cn": {"jdoe"}, "sn": {"Doe"}, "userPassword": {"other"}})
+
+	config := map[string]interface{}{
+		"dn": dn,
+		"sensitive_attributes": testAttributesConfig(map[string][]string{
+			"userPassword": {"secret"},
+		}),
+	}
+	state := testApply(t, r, nil, config, meta)
+	if values := s.Values(dn, "userPassword"); !testSameValues(values, []string{"other", "secret"}) {
+		t.Errorf("Unexpected passwords %v", values)
+	}
+
+	// only the owned values are changed
+	config["sensitive_attributes"] = testAttributesConfig(map[string][]string{
+		"userPassword": {"changed"},
+	})
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "userPassword"); !testSameValues(values, []string{"other", "changed"}) {
+		t.Errorf("Unexpected passwords %v", values)
+	}
+
+	// and removed when the resource is destroyed
+	testDestroy(t, r, state, meta)
+	if values := s.Values(dn, "userPassword"); !testSameValues(values, []string{"other"}) {
+		t.Errorf("Expected only the owned password to be removed, got %v", values)
+	}
+	if !testSameValues(s.Values(dn, "sn"), []string{"Doe"}) {
+		t.Errorf("Expected the other attributes to be untouched")
+	}
+}
+
+func TestAccLDAPObjectAttributes(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	dn := "cn=admins," + testBaseDN
+	s.PutEntry(dn, map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {"admins"},
+		"member":      {"cn=root," + testBaseDN},
+	})
+	config := func(member string) string {
+		return testAccProviderConfig(s) + fmt.Sprintf(`
+resource "ldap_object_attributes" "admins" {
+  dn = %q
+  attributes = [
+    { member = %q },
+  ]
+}
+`, dn, member)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testCheckLDAPValues(s, dn, "member", "cn=root,"+testBaseDN),
+		Steps: []resource.TestStep{
+			{
+				Config: config("cn=jdoe," + testBaseDN),
+				Check:  testCheckLDAPValues(s, dn, "member", "cn=root,"+testBaseDN, "cn=jdoe,"+testBaseDN),
+			},
+			{
+				Config: config("cn=bob," + testBaseDN),
+				Check:  testCheckLDAPValues(s, dn, "member", "cn=root,"+testBaseDN, "cn=bob,"+testBaseDN),
+			},
+			{
+				PreConfig: func() {
+					s.SetAttribute(dn, "member", "cn=root,"+testBaseDN)
+				},
+				Config:             config("cn=bob," + testBaseDN),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config("cn=bob," + testBaseDN),
+				Check:  testCheckLDAPValues(s, dn, "member", "cn=root,"+testBaseDN, "cn=bob,"+testBaseDN),
+			},
+			{
+				ResourceName:      "ldap_object_attributes.admins",
+				ImportState:       true,
+				ImportStateId:     dn + "|member=cn=bob," + testBaseDN,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestLDAPObjectAttributesWriteOptions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObjectAttributes()
+	dn := "cn=admins," + testBaseDN
+	s.PutEntry(dn, map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {"admins"},
+		"member":      {"cn=root," + testBaseDN, "cn=jdoe," + testBaseDN},
+	})
+	config := map[string]interface{}{
+		"dn": dn,
+		"attributes": testAttributesConfig(map[string][]string{
+			"member": {"cn=jdoe," + testBaseDN},
+		}),
+	}
+
+	// adding a value that exists already fails without the control
+	diff, err := r.Diff(ctx, nil, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	if _, diags := r.Apply(ctx, nil, diff, meta); !diags.HasError() {
+		t.Fatalf("Expected adding an existing value to fail")
+	}
+
+	// and succeeds with it, as does deleting a value that is gone
+	config["permissive_modify"] = true
+	state := testApply(t, r, nil, config, meta)
+	s.SetAttribute(dn, "member", "cn=root,"+testBaseDN)
+	testDestroy(t, r, state, meta)
+	if values := s.Values(dn, "member"); !testSameValues(values, []string{"cn=root," + testBaseDN}) {
+		t.Errorf("Unexpected member values %v", values)
+	}
+
+	// the provider option applies to all resources
+	meta.(*Client).SetWriteOptions(true, false)
+	delete(config, "permissive_modify")
+	s.SetAttribute(dn, "member", "cn=root,"+testBaseDN, "cn=jdoe,"+testBaseDN)
+	testApply(t, r, nil, config, meta)
+
+	// operational attributes can only be set with the relax rules control
+	uuid := "11111111-2222-4333-8444-555555555555"
+	object := resourceLDAPObject()
+	config = map[string]interface{}{
+		"dn":             "ou=migrated," + testBaseDN,
+		"object_classes": []interface{}{"organizationalUnit"},
+		"attributes": testAttributesConfig(map[string][]string{
+			"entryUUID": {uuid},
+		}),
+	}
+	diff, err = object.Diff(ctx, nil, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	if _, diags := object.Apply(ctx, nil, diff, meta); !diags.HasError() {
+		t.Fatalf("Expected setting entryUUID to fail")
+	}
+	config["relax_rules"] = true
+	testApply(t, object, nil, config, meta)
+	if values := s.Values("ou=migrated,"+testBaseDN, "entryUUID"); !testSameValues(values, []string{uuid}) {
+		t.Errorf("Unexpected entryUUID %v", values)
+	}
+}
@@ -0,0 +1,751 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestParseLDAPObjectImportID(t *testing.T) {
+	dn, skip, selected, err := parseLDAPObjectImportID("cn=jdoe,dc=example,dc=com|skip=userPassword, description|select=cn,sn")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dn != "cn=jdoe,dc=example,dc=com" {
+		t.Errorf("Unexpected DN %q", dn)
+	}
+	if !reflect.DeepEqual(skip, []string{"userPassword", "description"}) {
+		t.Errorf("Unexpected attributes to skip %v", skip)
+	}
+	if !reflect.DeepEqual(selected, []string{"cn", "sn"}) {
+		t.Errorf("Unexpected attributes to select %v", selected)
+	}
+
+	dn, skip, selected, err = parseLDAPObjectImportID("cn=jdoe,dc=example,dc=com")
+	if err != nil || dn != "cn=jdoe,dc=example,dc=com" || len(skip) != 0 || len(selected) != 0 {
+		t.Errorf("Unexpected result for plain DN: %q %v %v %v", dn, skip, selected, err)
+	}
+
+	for _, id := range []string{"", "|skip=cn", "cn=jdoe,dc=example,dc=com|cn", "cn=jdoe,dc=example,dc=com|only=cn"} {
+		if _, _, _, err := parseLDAPObjectImportID(id); err == nil {
+			t.Errorf("Expected an error for %q", id)
+		}
+	}
+}
+
+func TestIsServerManagedAttribute(t *testing.T) {
+	if !isServerManagedAttribute("whenChanged", []string{"top", "organizationalUnit"}) {
+		t.Errorf("Expected whenChanged to be server-managed")
+	}
+	if !isServerManagedAttribute("logonCount", []string{"top", "person", "organizationalPerson", "User"}) {
+		t.Errorf("Expected logonCount to be server-managed for users")
+	}
+	if isServerManagedAttribute("logonCount", []string{"top", "organizationalUnit"}) {
+		t.Errorf("Expected logonCount not to be server-managed for organizational units")
+	}
+	if isServerManagedAttribute("description", []string{"top", "person", "user"}) {
+		t.Errorf("Expected description not to be server-managed")
+	}
+}
+
+// testSameValues checks whether both lists hold the same values in any order.
+func testSameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, v := range a {
+		if !containsEqualFold(b, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLDAPObjectLifecycle(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"inetOrgPerson"},
+		"attributes": testAttributesConfig(map[string][]string{
+			"sn":          {"Doe"},
+			"mail":        {"jdoe@example.com", "john.doe@example.com"},
+			"description": {"a user"},
+		}),
+	}
+
+	// create
+	state := testApply(t, r, nil, config, meta)
+	if state.ID != dn {
+		t.Fatalf("Expected the ID to be %q, got %q", dn, state.ID)
+	}
+	if values := s.Values(dn, "mail"); !testSameValues(values, []string{"jdoe@example.com", "john.doe@example.com"}) {
+		t.Errorf("Unexpected mail values %v", values)
+	}
+	attributes := testAttributes(r, state)
+	if _, ok := attributes["cn"]; ok {
+		t.Errorf("Expected the RDN not to be part of the state, got %v", attributes)
+	}
+	if !testPlanIsEmpty(t, r, testRefresh(t, r, state, meta), config, meta) {
+		t.Errorf("Expected no changes after creation")
+	}
+
+	// update: change a value, drop an attribute, add and remove values
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"sn":   {"Smith"},
+		"mail": {"jdoe@example.com", "jsmith@example.com"},
+	})
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "sn"); !testSameValues(values, []string{"Smith"}) {
+		t.Errorf("Unexpected sn values %v", values)
+	}
+	if values := s.Values(dn, "mail"); !testSameValues(values, []string{"jdoe@example.com", "jsmith@example.com"}) {
+		t.Errorf("Unexpected mail values %v", values)
+	}
+	if values := s.Values(dn, "description"); len(values) != 0 {
+		t.Errorf("Expected description to be removed, got %v", values)
+	}
+
+	// drift is detected and reverted
+	s.SetAttribute(dn, "description", "drifted")
+	state = testRefresh(t, r, state, meta)
+	if values := testAttributes(r, state)["description"]; !testSameValues(values, []string{"drifted"}) {
+		t.Errorf("Expected the drift to be detected, got %v", values)
+	}
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "description"); len(values) != 0 {
+		t.Errorf("Expected the drift to be reverted, got %v", values)
+	}
+
+	// import, skipping some attributes
+	imported := testImport(t, r, dn+"|skip=mail", meta)
+	if imported.ID != dn {
+		t.Errorf("Expected the imported ID to be %q, got %q", dn, imported.ID)
+	}
+	attributes = testAttributes(r, imported)
+	if _, ok := attributes["mail"]; ok {
+		t.Errorf("Expected mail to be skipped on import, got %v", attributes)
+	}
+	if !testSameValues(attributes["sn"], []string{"Smith"}) {
+		t.Errorf("Unexpected imported attributes %v", attributes)
+	}
+
+	// destroy
+	testDestroy(t, r, state, meta)
+	if s.Entry(dn) != nil {
+		t.Errorf("Expected %q to be deleted", dn)
+	}
+	if state := testRefresh(t, r, state, meta); state != nil {
+		t.Errorf("Expected the resource to be gone, got %v", state)
+	}
+}
+
+func TestLDAPObjectAdoptExisting(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+	s.PutEntry(dn, map[string][]string{
+		"objectClass": {"person"},
+		"cn":          {"jdoe"},
+		"sn":          {"Doe"},
+		"description": {"pre-seeded"},
+	})
+
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"inetOrgPerson"},
+		"attributes": testAttributesConfig(map[string][]string{
+			"sn":   {"Doe"},
+			"mail": {"jdoe@example.com"},
+		}),
+		"adopt_existing": true,
+	}
+	state := testApply(t, r, nil, config, meta)
+	if state.ID != dn {
+		t.Fatalf("Expected %q to be adopted", dn)
+	}
+	if values := s.Values(dn, "objectClass"); !testSameValues(values, []string{"inetOrgPerson"}) {
+		t.Errorf("Unexpected classes %v", values)
+	}
+	if values := s.Values(dn, "description"); len(values) != 0 {
+		t.Errorf("Expected description to be removed, got %v", values)
+	}
+	if values := s.Values(dn, "mail"); !testSameValues(values, []string{"jdoe@example.com"}) {
+		t.Errorf("Unexpected mail values %v", values)
+	}
+}
+
+func TestLDAPObjectAdoptExistingServerManaged(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	meta.(*Client).SetDefaults("", []string{"description"})
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+	// Active Directory returns server-managed attributes among the user ones
+	s.PutEntry(dn, map[string][]string{
+		"objectClass":  {"top", "person", "organizationalPerson", "user"},
+		"cn":           {"jdoe"},
+		"sn":           {"Doe"},
+		"description":  {"set elsewhere"},
+		"objectGUID":   {"guid"},
+		"whenCreated":  {"20240101000000.0Z"},
+		"instanceType": {"4"},
+		"logonCount":   {"3"},
+	})
+
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"top", "person", "organizationalPerson", "user"},
+		"attributes":     testAttributesConfig(map[string][]string{"sn": {"Smith"}}),
+		"adopt_existing": true,
+	}
+	state := testApply(t, r, nil, config, meta)
+	if values := s.Values(dn, "sn"); !testSameValues(values, []string{"Smith"}) {
+		t.Errorf("Unexpected sn values %v", values)
+	}
+	for _, name := range []string{"description", "objectGUID", "whenCreated", "instanceType", "logonCount"} {
+		if len(s.Values(dn, name)) == 0 {
+			t.Errorf("Expected %s to be left alone", name)
+		}
+	}
+	if !testPlanIsEmpty(t, r, testRefresh(t, r, state, meta), config, meta) {
+		t.Errorf("Expected no changes after adopting %q", dn)
+	}
+}
+
+func TestAccLDAPObject(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	dn := "cn=jdoe," + testBaseDN
+	config := func(sn string) string {
+		return testAccProviderConfig(s) + fmt.Sprintf(`
+resource "ldap_object" "jdoe" {
+  dn             = %q
+  object_classes = ["inetOrgPerson"]
+  attributes = [
+    { sn = %q },
+    { mail = "jdoe@example.com" },
+  ]
+}
+`, dn, sn)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if s.Entry(dn) != nil {
+				return fmt.Errorf("%q still exists", dn)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config("Doe"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("ldap_object.jdoe", "id", dn),
+					resource.TestCheckResourceAttr("ldap_object.jdoe", "attributes.#", "2"),
+					testCheckLDAPValues(s, dn, "sn", "Doe"),
+				),
+			},
+			{
+				Config: config("Smith"),
+				Check:  testCheckLDAPValues(s, dn, "sn", "Smith"),
+			},
+			{
+				PreConfig: func() {
+					s.SetAttribute(dn, "description", "drifted")
+				},
+				Config:             config("Smith"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config("Smith"),
+				Check:  testCheckLDAPValues(s, dn, "description"),
+			},
+			{
+				ResourceName:      "ldap_object.jdoe",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"adopt_existing",
+				},
+			},
+		},
+	})
+}
+
+// testCheckLDAPValues checks the values of an attribute on the server.
+func testCheckLDAPValues(s *testLDAPServer, dn, name string, values ...string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if actual := s.Values(dn, name); !testSameValues(actual, values) {
+			return fmt.Errorf("Expected %s of %q to be %v, got %v", name, dn, values, actual)
+		}
+		return nil
+	}
+}
+
+func TestLDAPObjectSensitiveAttributes(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"inetOrgPerson"},
+		"attributes":     testAttributesConfig(map[string][]string{"sn": {"Doe"}}),
+		"sensitive_attributes": testAttributesConfig(map[string][]string{
+			"userPassword": {"secret"},
+		}),
+	}
+	state := testApply(t, r, nil, config, meta)
+	if values := s.Values(dn, "userPassword"); !testSameValues(values, []string{"secret"}) {
+		t.Errorf("Unexpected password %v", values)
+	}
+	if _, ok := testAttributes(r, state)["userPassword"]; ok {
+		t.Errorf("Expected the password not to be among the attributes")
+	}
+
+	// the server hashes the password, which must not cause drift
+	s.SetAttribute(dn, "userPassword", "{SSHA}c2VjcmV0")
+	state = testRefresh(t, r, state, meta)
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes for a hashed password")
+	}
+
+	// changing the password replaces it
+	config["sensitive_attributes"] = testAttributesConfig(map[string][]string{
+		"userPassword": {"changed"},
+	})
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "userPassword"); !testSameValues(values, []string{"changed"}) {
+		t.Errorf("Unexpected password %v", values)
+	}
+
+	// removing it removes the attribute
+	delete(config, "sensitive_attributes")
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "userPassword"); len(values) != 0 {
+		t.Errorf("Expected the password to be removed, got %v", values)
+	}
+	if !testSameValues(s.Values(dn, "sn"), []string{"Doe"}) {
+		t.Errorf("Expected the other attributes to be untouched")
+	}
+}
+
+func TestLDAPObjectDryRun(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+
+	var ldif strings.Builder
+	meta.(*Client).SetLDIFOutput(&ldif, true)
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"inetOrgPerson"},
+		"attributes":     testAttributesConfig(map[string][]string{"sn": {"Doe"}}),
+		"sensitive_attributes": testAttributesConfig(map[string][]string{
+			"employeeNumber": {"42"},
+		}),
+	}
+
+	// nothing is sent, so the apply fails and nothing is saved to state
+	ctx := context.Background()
+	diff, err := r.Diff(ctx, nil, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	state, diags := r.Apply(ctx, nil, diff, meta)
+	if !diags.HasError() || diags[0].Summary != "Dry run" {
+		t.Errorf("Expected the dry run to fail the apply, got %v", diags)
+	}
+	if state != nil && state.ID != "" {
+		t.Errorf("Expected no state, got %v", state)
+	}
+	if s.Entry(dn) != nil {
+		t.Errorf("Expected %q not to be added in a dry run", dn)
+	}
+	if !strings.HasPrefix(ldif.String(), "version: 1\n\ndn: "+dn+"\n") {
+		t.Errorf("Expected the add request to be written, got:\n%s", ldif.String())
+	}
+	if strings.Contains(ldif.String(), "42") || !strings.Contains(ldif.String(), "# 1 value(s) of employeeNumber redacted\n") {
+		t.Errorf("Expected the sensitive attribute to be redacted, got:\n%s", ldif.String())
+	}
+
+	// destroying keeps the object in state, as it is still there
+	meta.(*Client).SetLDIFOutput(nil, false)
+	state = testApply(t, r, nil, config, meta)
+	meta.(*Client).SetLDIFOutput(&ldif, true)
+	destroyed, diags := r.Apply(ctx, state, &terraform.InstanceDiff{Destroy: true}, meta)
+	if !diags.HasError() || destroyed == nil || destroyed.ID != dn {
+		t.Errorf("Expected the dry run to keep the object in state, got %v and %v", destroyed, diags)
+	}
+	if s.Entry(dn) == nil {
+		t.Errorf("Expected %q not to be deleted in a dry run", dn)
+	}
+}
+
+func TestLDAPObjectAttributePolicy(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"inetOrgPerson"},
+		"attributes": testAttributesConfig(map[string][]string{
+			"sn":              {"Doe"},
+			"description":     {"a"},
+			"telephoneNumber": {"1"},
+		}),
+		"attribute_policy": []interface{}{
+			map[string]interface{}{"name": "objectClass", "mode": "additive"},
+			map[string]interface{}{"name": "description", "mode": "additive"},
+			map[string]interface{}{"name": "telephoneNumber", "mode": "create_only"},
+		},
+	}
+	state := testApply(t, r, nil, config, meta)
+
+	// values added and changed behind the back of the provider are ignored
+	s.SetAttribute(dn, "objectClass", "inetOrgPerson", "extensibleObject")
+	s.SetAttribute(dn, "description", "a", "other")
+	s.SetAttribute(dn, "telephoneNumber", "2")
+	state = testRefresh(t, r, state, meta)
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes for values not owned by the provider")
+	}
+
+	// additive values are added and removed one by one, create-only ones are
+	// left alone
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"sn":              {"Doe"},
+		"description":     {"b"},
+		"telephoneNumber": {"3"},
+	})
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "description"); !testSameValues(values, []string{"other", "b"}) {
+		t.Errorf("Unexpected description %v", values)
+	}
+	if values := s.Values(dn, "telephoneNumber"); !testSameValues(values, []string{"2"}) {
+		t.Errorf("Unexpected telephone number %v", values)
+	}
+	if values := s.Values(dn, "objectClass"); !testSameValues(values, []string{"inetOrgPerson", "extensibleObject"}) {
+		t.Errorf("Unexpected classes %v", values)
+	}
+	state = testRefresh(t, r, state, meta)
+	if testAttributes(r, state)["telephoneNumber"][0] != "1" {
+		t.Errorf("Expected the state to keep the create-only value, got %v", testAttributes(r, state)["telephoneNumber"])
+	}
+	if testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected the changed create-only value to keep showing in plans")
+	}
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"sn":              {"Doe"},
+		"description":     {"b"},
+		"telephoneNumber": {"1"},
+	})
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes after applying the policies")
+	}
+
+	// the authoritative attributes are still owned fully
+	s.SetAttribute(dn, "sn", "Doe", "Smith")
+	state = testRefresh(t, r, state, meta)
+	testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "sn"); !testSameValues(values, []string{"Doe"}) {
+		t.Errorf("Unexpected surname %v", values)
+	}
+
+	config["attribute_policy"] = append(config["attribute_policy"].([]interface{}),
+		map[string]interface{}{"name": "Description", "mode": "authoritative"})
+	if _, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), meta); err == nil {
+		t.Errorf("Expected an error for an attribute with two policies")
+	}
+}
+
+func TestLDAPObjectProviderDefaults(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	meta.(*Client).SetDefaults(testBaseDN, []string{"description"})
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+
+	config := map[string]interface{}{
+		"dn":             "cn=jdoe",
+		"object_classes": []interface{}{"inetOrgPerson"},
+		"attributes":     testAttributesConfig(map[string][]string{"sn": {"Doe"}}),
+	}
+	state := testApply(t, r, nil, config, meta)
+	if s.Entry(dn) == nil {
+		t.Fatalf("Expected %q to be created", dn)
+	}
+	if state.ID != dn || state.Attributes["dn"] != "cn=jdoe" {
+		t.Errorf("Expected the configured DN in state and the absolute one as ID, got %q and %q", state.Attributes["dn"], state.ID)
+	}
+
+	// the attributes skipped by default are not tracked
+	s.SetAttribute(dn, "description", "set elsewhere")
+	state = testRefresh(t, r, state, meta)
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes for attributes skipped by default")
+	}
+
+	// imported objects keep the DN they are imported with
+	imported := testImport(t, r, "cn=jdoe", meta)
+	if imported.ID != dn || imported.Attributes["dn"] != "cn=jdoe" || !testPlanIsEmpty(t, r, imported, config, meta) {
+		t.Errorf("Expected the imported object to match the configuration, got DN %q", imported.Attributes["dn"])
+	}
+	imported = testImport(t, r, dn, meta)
+	if imported.ID != dn || imported.Attributes["dn"] != dn {
+		t.Errorf("Expected the imported object to keep its absolute DN, got %q", imported.Attributes["dn"])
+	}
+
+	testDestroy(t, r, state, meta)
+	if s.Entry(dn) != nil {
+		t.Errorf("Expected %q to be deleted", dn)
+	}
+}
+
+func TestLDAPObjectClassChanges(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	s.SetObjectClasses(
+		"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+		"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) )",
+		"( 2.5.6.7 NAME 'organizationalPerson' SUP person STRUCTURAL )",
+		"( 2.16.840.1.113730.3.2.2 NAME 'inetOrgPerson' SUP organizationalPerson STRUCTURAL )",
+		"( 1.3.6.1.1.1.2.0 NAME 'posixAccount' SUP top AUXILIARY )",
+		"( 1.3.6.1.4.1.1466.101.120.111 NAME 'extensibleObject' SUP top AUXILIARY )",
+	)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "cn=jdoe," + testBaseDN
+
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"top", "person", "organizationalPerson", "inetOrgPerson", "posixAccount"},
+		"attributes":     testAttributesConfig(map[string][]string{"sn": {"Doe"}}),
+	}
+	state := testApply(t, r, nil, config, meta)
+
+	// auxiliary classes are added and removed one by one
+	var ldif strings.Builder
+	meta.(*Client).SetLDIFOutput(&ldif, false)
+	config["object_classes"] = []interface{}{"top", "person", "organizationalPerson", "inetOrgPerson", "extensibleObject"}
+	state = testApply(t, r, state, config, meta)
+	if !strings.Contains(ldif.String(), "add: objectClass\nobjectClass: extensibleObject\n-\ndelete: objectClass\nobjectClass: posixAccount\n") {
+		t.Errorf("Expected individual classes to be added and removed, got:\n%s", ldif.String())
+	}
+	if values := s.Values(dn, "objectClass"); !testSameValues(values, []string{"top", "person", "organizationalPerson", "inetOrgPerson", "extensibleObject"}) {
+		t.Errorf("Unexpected classes %v", values)
+	}
+
+	// structural classes can't be changed in place
+	config["object_classes"] = []interface{}{"top", "person", "extensibleObject"}
+	if _, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), meta); err == nil {
+		t.Errorf("Expected an error when changing the structural class")
+	}
+	config["recreate_on_structural_change"] = true
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil || !diff.RequiresNew() {
+		t.Errorf("Expected the object to be recreated, got %v (%v)", diff, err)
+	}
+}
+
+func TestLDAPObjectModifyStrategy(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "cn=staff," + testBaseDN
+
+	members := []string{}
+	for i := 0; i < deltaStrategyThreshold; i++ {
+		members = append(members, fmt.Sprintf("uid=user%d,%s", i, testBaseDN))
+	}
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"groupOfNames"},
+		"attributes": testAttributesConfig(map[string][]string{
+			"member":      members,
+			"description": {"a", "b"},
+		}),
+	}
+	state := testApply(t, r, nil, config, meta)
+
+	var ldif strings.Builder
+	meta.(*Client).SetLDIFOutput(&ldif, false)
+
+	// large attributes get deltas, small ones are replaced
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"member":      append(members[1:], "uid=new,"+testBaseDN),
+		"description": {"a", "c"},
+	})
+	state = testApply(t, r, state, config, meta)
+	for _, change := range []string{
+		"delete: member\nmember: uid=user0," + testBaseDN + "\n-\nadd: member\nmember: uid=new," + testBaseDN + "\n",
+		"replace: description\n",
+	} {
+		if !strings.Contains(ldif.String(), change) {
+			t.Errorf("Expected the change %q, got:\n%s", change, ldif.String())
+		}
+	}
+	if values := s.Values(dn, "member"); len(values) != deltaStrategyThreshold || !testContains(values, "uid=new,"+testBaseDN) {
+		t.Errorf("Unexpected members %v", values)
+	}
+
+	// the strategy can be set per attribute
+	ldif.Reset()
+	config["modify_strategy"] = "replace"
+	config["attribute_policy"] = []interface{}{
+		map[string]interface{}{"name": "description", "modify_strategy": "delta"},
+	}
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"member":      members,
+		"description": {"a", "d"},
+	})
+	testApply(t, r, state, config, meta)
+	for _, change := range []string{
+		"replace: member\n",
+		"delete: description\ndescription: c\n-\nadd: description\ndescription: d\n",
+	} {
+		if !strings.Contains(ldif.String(), change) {
+			t.Errorf("Expected the change %q, got:\n%s", change, ldif.String())
+		}
+	}
+}
+
+func TestLDAPObjectAssertPriorValues(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "uid=jdoe," + testBaseDN
+
+	config := map[string]interface{}{
+		"dn":                  dn,
+		"object_classes":      []interface{}{"inetOrgPerson"},
+		"assert_prior_values": true,
+		"attributes": testAttributesConfig(map[string][]string{
+			"cn":          {"John Doe"},
+			"sn":          {"Doe"},
+			"description": {"a"},
+		}),
+	}
+	state := testApply(t, r, nil, config, meta)
+
+	// unchanged values pass the assertion
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"cn":          {"John Doe"},
+		"sn":          {"Doe"},
+		"description": {"b"},
+		"mail":        {"jdoe@example.com"},
+	})
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "description"); len(values) != 1 || values[0] != "b" {
+		t.Errorf("Unexpected description %v", values)
+	}
+
+	// a concurrent change fails the update instead of being overwritten
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"cn":          {"John Doe"},
+		"sn":          {"Doe"},
+		"description": {"c"},
+		"mail":        {"jdoe@example.com"},
+	})
+	diff, err := r.Diff(ctx, state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	s.SetAttribute(dn, "description", "changed")
+	_, diags := r.Apply(ctx, state, diff, meta)
+	if !diags.HasError() || !strings.HasPrefix(diags[0].Summary, "Entry changed since plan") {
+		t.Fatalf("Expected the update to fail the assertion, got %v", diags)
+	}
+	if values := s.Values(dn, "description"); len(values) != 1 || values[0] != "changed" {
+		t.Errorf("Expected the concurrent change to be kept, got %v", values)
+	}
+
+	// so does an attribute added concurrently
+	state = testRefresh(t, r, state, meta)
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"cn":          {"John Doe"},
+		"sn":          {"Doe"},
+		"description": {"changed"},
+		"mail":        {"jdoe@example.com"},
+		"title":       {"Engineer"},
+	})
+	diff, err = r.Diff(ctx, state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	s.SetAttribute(dn, "title", "Manager")
+	if _, diags := r.Apply(ctx, state, diff, meta); !diags.HasError() {
+		t.Fatalf("Expected the update to fail the assertion")
+	}
+}
+
+func TestLDAPObjectProxiedAuthorization(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPObject()
+	dn := "ou=tenant," + testBaseDN
+	delegate, other := "uid=delegate,"+testBaseDN, "uid=other,"+testBaseDN
+	s.PutEntry(delegate, map[string][]string{"objectClass": {"account"}, "uid": {"delegate"}})
+	s.PutEntry(other, map[string][]string{"objectClass": {"account"}, "uid": {"other"}})
+
+	// the identity of the provider applies to all requests
+	meta.(*Client).SetProxiedAuthorization("dn:" + delegate)
+	config := map[string]interface{}{
+		"dn":             dn,
+		"object_classes": []interface{}{"organizationalUnit"},
+		"attributes": testAttributesConfig(map[string][]string{
+			"description": {"a"},
+		}),
+	}
+	state := testApply(t, r, nil, config, meta)
+	if values := s.Values(dn, "creatorsName"); !testSameValues(values, []string{delegate}) {
+		t.Errorf("Expected %q to be created by %q, got %v", dn, delegate, values)
+	}
+
+	// and resources can override it
+	config["proxied_authorization"] = "u:other"
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"description": {"b"},
+	})
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "modifiersName"); !testSameValues(values, []string{other}) {
+		t.Errorf("Expected %q to be modified by %q, got %v", dn, other, values)
+	}
+
+	// identities the server doesn't accept fail the requests
+	config["proxied_authorization"] = "u:nobody"
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"description": {"c"},
+	})
+	diff, err := r.Diff(ctx, state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	_, diags := r.Apply(ctx, state, diff, meta)
+	if !diags.HasError() || !strings.HasPrefix(diags[0].Summary, "Proxied authorization denied") {
+		t.Fatalf("Expected the authorization to be denied, got %v", diags)
+	}
+	if values := s.Values(dn, "description"); !testSameValues(values, []string{"b"}) {
+		t.Errorf("Unexpected description %v", values)
+	}
+
+	if _, errs := validateAuthzID("cn=admin", "proxied_authorization"); len(errs) == 0 {
+		t.Errorf("Expected an authorization identity without prefix to be rejected")
+	}
+}
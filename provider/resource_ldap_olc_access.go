@@ -0,0 +1,440 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/pkg/errors"
+)
+
+func resourceLDAPOlcAccess() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceLDAPOlcAccessCreate,
+		ReadContext:   resourceLDAPOlcAccessRead,
+		UpdateContext: resourceLDAPOlcAccessUpdate,
+		DeleteContext: resourceLDAPOlcAccessDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Read:   schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceLDAPOlcAccessImport,
+		},
+
+		Description: "The `ldap_olc_access`-resource owns the ordered access control rules (`olcAccess`) of an OpenLDAP database configured in cn=config; as the database lives there, the `base_dn` of the provider does not apply.",
+
+		Schema: map[string]*schema.Schema{
+			"database_dn": {
+				Type:        schema.TypeString,
+				Description: "The DN of the database entry holding the rules, e.g. `olcDatabase={1}mdb,cn=config`.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"rule": {
+				Type:        schema.TypeList,
+				Description: "The access control rules, in the order they are evaluated by the server.",
+				Required:    true,
+				MinItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"to": {
+							Type:        schema.TypeString,
+							Description: "What the rule grants access to, e.g. `*` or `attrs=userPassword`.",
+							Required:    true,
+						},
+						"by": {
+							Type:        schema.TypeList,
+							Description: "Who is granted which access, in order.",
+							Required:    true,
+							MinItems:    1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"who": {
+										Type:        schema.TypeString,
+										Description: "Who is granted access, e.g. `self`, `users` or `dn.exact=\"cn=admin,dc=example,dc=com\"`.",
+										Required:    true,
+									},
+									"access": {
+										Type:        schema.TypeString,
+										Description: "The access level or privileges granted, e.g. `read`, `write` or `=rscx`.",
+										Required:    true,
+									},
+									"control": {
+										Type:         schema.TypeString,
+										Description:  "How evaluation continues after this clause matched: stop, continue or break.",
+										Optional:     true,
+										ValidateFunc: validation.StringInSlice([]string{"stop", "continue", "break"}, false),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceLDAPOlcAccessImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	dn := d.Id()
+	debugLog("ldap_olc_access::import - going to import dn %q", dn)
+	d.Set("database_dn", dn)
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceLDAPOlcAccessCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	dn := d.Get("database_dn").(string)
+
+	debugLog("ldap_olc_access::create - setting access rules of %q", dn)
+
+	rules := renderOlcAccessRules(d.Get("rule").([]interface{}))
+	values := make([]string, 0, len(rules))
+	for i, rule := range rules {
+		values = append(values, fmt.Sprintf("{%d}%s", i, rule))
+	}
+
+	// the resource owns all the rules of the database, so whatever is there
+	// already gets replaced
+	modify := ldap.NewModifyRequest(dn, []ldap.Control{})
+	modify.Replace("olcAccess", values)
+	if err := client.Modify(ctx, modify); err != nil {
+		errorLog("ldap_olc_access::create - error setting access rules of %q: %v", dn, err)
+		return ldapDiagnostics(err, dn, cty.GetAttrPath("rule"))
+	}
+
+	d.SetId(dn)
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPOlcAccessRead(ctx, d, meta)
+}
+
+func resourceLDAPOlcAccessRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	dn := d.Get("database_dn").(string)
+
+	debugLog("ldap_olc_access::read - looking for access rules of %q", dn)
+
+	request := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectClass=*)",
+		[]string{"olcAccess"},
+		nil,
+	)
+
+	sr, err := client.Search(ctx, request)
+	if err != nil {
+		if err, ok := err.(*ldap.Error); ok {
+			if err.ResultCode == 32 { // no such object
+				warnLog("ldap_olc_access::read - database not found, removing %q from state because it no longer exists in LDAP", dn)
+				d.SetId("")
+				return nil
+			}
+		}
+		debugLog("ldap_olc_access::read - lookup for %q returned an error %v", dn, err)
+		return diag.FromErr(err)
+	}
+
+	if len(sr.Entries) == 0 {
+		return diag.FromErr(fmt.Errorf("Database %q not found", dn))
+	}
+
+	// the server stores the rules in its normal form, so rules that are
+	// equivalent to the ones in the state are kept as they are written there
+	prior := d.Get("rule").([]interface{})
+	priorRules := renderOlcAccessRules(prior)
+	rules := []interface{}{}
+	for i, value := range sortByOrderingPrefix(sr.Entries[0].GetEqualFoldAttributeValues("olcAccess")) {
+		if i < len(priorRules) && normalizeOlcAccessRule(priorRules[i]) == normalizeOlcAccessRule(value) {
+			rules = append(rules, prior[i])
+			continue
+		}
+		rule, err := parseOlcAccessRule(value)
+		if err != nil {
+			return diag.FromErr(errors.Wrapf(err, "Parsing access rules of %q", dn))
+		}
+		rules = append(rules, rule)
+	}
+	debugLog("ldap_olc_access::read - %q has %d access rules", dn, len(rules))
+
+	if err := d.Set("rule", rules); err != nil {
+		warnLog("ldap_olc_access::read - error setting access rules for %q : %v", dn, err)
+		return diag.FromErr(err)
+	}
+	d.SetId(dn)
+	return nil
+}
+
+func resourceLDAPOlcAccessUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	dn := d.Get("database_dn").(string)
+
+	debugLog("ldap_olc_access::update - performing update on %q", dn)
+
+	modify := ldap.NewModifyRequest(dn, []ldap.Control{})
+
+	if d.HasChange("rule") {
+		o, n := d.GetChange("rule")
+		computeOlcAccessDeltas(modify, renderOlcAccessRules(o.([]interface{})), renderOlcAccessRules(n.([]interface{})))
+	}
+
+	if len(modify.Changes) > 0 {
+		err := client.Modify(ctx, modify)
+		if err != nil {
+			errorLog("ldap_olc_access::update - error modifying access rules of %q: %v", dn, err)
+			return ldapDiagnostics(err, dn, cty.GetAttrPath("rule"))
+		}
+	} else {
+		warnLog("ldap_olc_access::update - didn't actually make changes to %q because there were no changes requested", dn)
+	}
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPOlcAccessRead(ctx, d, meta)
+}
+
+func resourceLDAPOlcAccessDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	dn := d.Get("database_dn").(string)
+
+	debugLog("ldap_olc_access::delete - removing access rules of %q", dn)
+
+	modify := ldap.NewModifyRequest(dn, []ldap.Control{})
+	modify.Delete("olcAccess", []string{})
+	err := client.Modify(ctx, modify)
+	if err != nil {
+		if err, ok := err.(*ldap.Error); ok {
+			if err.ResultCode == ldap.LDAPResultNoSuchAttribute || err.ResultCode == ldap.LDAPResultNoSuchObject {
+				warnLog("ldap_olc_access::delete - access rules of %q are already gone", dn)
+				return nil
+			}
+		}
+		errorLog("ldap_olc_access::delete - error removing access rules of %q: %v", dn, err)
+		return ldapDiagnostics(err, dn, nil)
+	}
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	debugLog("ldap_olc_access::delete - access rules of %q removed", dn)
+	return nil
+}
+
+// computeOlcAccessDeltas adds the index-aware changes needed to turn the old
+// list of rules into the new one: the rules are kept up to the first one that
+// differs, all the following ones are deleted by index (from the last one, so
+// that the server does not renumber the ones still to be deleted) and the new
+// ones are added back with explicit indexes.
+func computeOlcAccessDeltas(modify *ldap.ModifyRequest, old, new []string) {
+	common := 0
+	for common < len(old) && common < len(new) && normalizeOlcAccessRule(old[common]) == normalizeOlcAccessRule(new[common]) {
+		common++
+	}
+	for i := len(old) - 1; i >= common; i-- {
+		debugLog("ldap_olc_access::deltas - deleting rule {%d}", i)
+		modify.Delete("olcAccess", []string{fmt.Sprintf("{%d}", i)})
+	}
+	if common < len(new) {
+		values := []string{}
+		for i := common; i < len(new); i++ {
+			values = append(values, fmt.Sprintf("{%d}%s", i, new[i]))
+		}
+		debugLog("ldap_olc_access::deltas - adding rules %v", values)
+		modify.Add("olcAccess", values)
+	}
+}
+
+// renderOlcAccessRules renders the rule blocks in the olcAccess syntax,
+// without ordering prefixes.
+func renderOlcAccessRules(blocks []interface{}) []string {
+	rules := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		m := b.(map[string]interface{})
+		parts := []string{"to", m["to"].(string)}
+		for _, by := range m["by"].([]interface{}) {
+			c := by.(map[string]interface{})
+			parts = append(parts, "by", c["who"].(string), c["access"].(string))
+			if control := c["control"].(string); control != "" {
+				parts = append(parts, control)
+			}
+		}
+		rules = append(rules, strings.Join(parts, " "))
+	}
+	return rules
+}
+
+// normalizeOlcAccessRule turns a rule into the normal form OpenLDAP stores
+// it in, so that rules can be compared as the server would: the ordering
+// prefix is stripped, whitespace outside of quoted strings is collapsed,
+// keywords are lowercased, DN styles are given their canonical names (e.g.
+// dn.exact becomes dn.base), DN patterns are quoted and lowercased, and the
+// default stop control is dropped.
+func normalizeOlcAccessRule(rule string) string {
+	_, rule = splitOrderingPrefix(strings.TrimSpace(rule))
+	tokens := tokenizeOlcAccessRule(rule)
+	normalized := make([]string, 0, len(tokens))
+	for i, t := range tokens {
+		last := i == len(tokens)-1 || strings.EqualFold(tokens[i+1], "by")
+		switch {
+		case strings.EqualFold(t, "stop") && last:
+			continue
+		case strings.EqualFold(t, "to") || strings.EqualFold(t, "by"):
+			t = strings.ToLower(t)
+		default:
+			t = normalizeOlcAccessToken(t)
+		}
+		normalized = append(normalized, t)
+	}
+	return strings.Join(normalized, " ")
+}
+
+// the canonical names of the DN styles
+var olcAccessDNStyles = map[string]string{
+	"":           "base",
+	"exact":      "base",
+	"base":       "base",
+	"baseobject": "base",
+	"one":        "one",
+	"onelevel":   "one",
+	"sub":        "subtree",
+	"subtree":    "subtree",
+	"children":   "children",
+	"regex":      "regex",
+}
+
+// normalizeOlcAccessToken normalizes a single token of a rule, e.g.
+// `DN.Exact="cn=Admin, dc=example"` becomes `dn.base="cn=admin,dc=example"`.
+func normalizeOlcAccessToken(t string) string {
+	eq := strings.IndexByte(t, '=')
+	if eq <= 0 {
+		return strings.ToLower(t)
+	}
+	key, value := strings.ToLower(t[:eq]), t[eq+1:]
+	if key == "attr" {
+		key = "attrs"
+	}
+	name, style := key, ""
+	if dot := strings.IndexByte(key, '.'); dot >= 0 {
+		name, style = key[:dot], key[dot+1:]
+	}
+	if name == "attrs" {
+		return key + "=" + strings.ToLower(value)
+	}
+	if name != "dn" {
+		return key + "=" + value
+	}
+	if canonical, ok := olcAccessDNStyles[style]; ok {
+		style = canonical
+	}
+	value = strings.Trim(value, `"`)
+	if style != "regex" {
+		if dn, err := ldap.ParseDN(value); err == nil {
+			rdns := make([]string, 0, len(dn.RDNs))
+			for _, rdn := range dn.RDNs {
+				avas := make([]string, 0, len(rdn.Attributes))
+				for _, ava := range rdn.Attributes {
+					avas = append(avas, ava.Type+"="+escapeDNValue(ava.Value))
+				}
+				rdns = append(rdns, strings.Join(avas, "+"))
+			}
+			value = strings.Join(rdns, ",")
+		}
+		value = strings.ToLower(value)
+	}
+	return "dn." + style + `="` + value + `"`
+}
+
+// parseOlcAccessRule parses a value of olcAccess into the structure of a rule
+// block.
+func parseOlcAccessRule(value string) (map[string]interface{}, error) {
+	_, value = splitOrderingPrefix(strings.TrimSpace(value))
+	tokens := tokenizeOlcAccessRule(value)
+	if len(tokens) < 2 || !strings.EqualFold(tokens[0], "to") {
+		return nil, fmt.Errorf("Invalid access rule %q: expected it to start with \"to\"", value)
+	}
+
+	// split the tokens into the "to" clause and each of the "by" clauses
+	clauses := [][]string{{}}
+	for _, t := range tokens[1:] {
+		if strings.EqualFold(t, "by") {
+			clauses = append(clauses, []string{})
+			continue
+		}
+		clauses[len(clauses)-1] = append(clauses[len(clauses)-1], t)
+	}
+	if len(clauses[0]) == 0 {
+		return nil, fmt.Errorf("Invalid access rule %q: missing what the rule grants access to", value)
+	}
+
+	bys := []interface{}{}
+	for _, clause := range clauses[1:] {
+		control := ""
+		if n := len(clause); n > 0 {
+			switch strings.ToLower(clause[n-1]) {
+			case "stop", "continue", "break":
+				control = strings.ToLower(clause[n-1])
+				clause = clause[:n-1]
+			}
+		}
+		if len(clause) < 2 {
+			return nil, fmt.Errorf("Invalid access rule %q: each \"by\" clause needs a who and an access level", value)
+		}
+		bys = append(bys, map[string]interface{}{
+			"who":     strings.Join(clause[:len(clause)-1], " "),
+			"access":  clause[len(clause)-1],
+			"control": control,
+		})
+	}
+	if len(bys) == 0 {
+		return nil, fmt.Errorf("Invalid access rule %q: missing \"by\" clauses", value)
+	}
+
+	return map[string]interface{}{
+		"to": strings.Join(clauses[0], " "),
+		"by": bys,
+	}, nil
+}
+
+// tokenizeOlcAccessRule splits a rule on whitespace, keeping double-quoted
+// strings (e.g. DNs containing spaces) within a single token.
+func tokenizeOlcAccessRule(rule string) []string {
+	tokens := []string{}
+	var current strings.Builder
+	quoted, escaped := false, false
+	for _, c := range rule {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(c)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestTokenizeOlcAccessRule(t *testing.T) {
+	for rule, expected := range map[string][]string{
+		`to *  by * read`: {"to", "*", "by", "*", "read"},
+		`to dn.base="cn=a b,dc=example" by self write`: {"to", `dn.base="cn=a b,dc=example"`, "by", "self", "write"},
+		`to attrs=userPassword by dn="cn=x\"y" =xw`:    {"to", "attrs=userPassword", "by", `dn="cn=x\"y"`, "=xw"},
+		"to *\n\tby users read":                        {"to", "*", "by", "users", "read"},
+	} {
+		if tokens := tokenizeOlcAccessRule(rule); !reflect.DeepEqual(tokens, expected) {
+			t.Errorf("Unexpected tokens for %q: %q", rule, tokens)
+		}
+	}
+}
+
+func TestParseOlcAccessRule(t *testing.T) {
+	rule, err := parseOlcAccessRule(`{2}to attrs=userPassword by self write by anonymous auth continue by * none`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"to": "attrs=userPassword",
+		"by": []interface{}{
+			map[string]interface{}{"who": "self", "access": "write", "control": ""},
+			map[string]interface{}{"who": "anonymous", "access": "auth", "control": "continue"},
+			map[string]interface{}{"who": "*", "access": "none", "control": ""},
+		},
+	}
+	if !reflect.DeepEqual(rule, expected) {
+		t.Errorf("Unexpected rule %v", rule)
+	}
+
+	for _, value := range []string{"", "by * read", "to *", "to * by read", "to by * read"} {
+		if _, err := parseOlcAccessRule(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestNormalizeOlcAccessRule(t *testing.T) {
+	for _, c := range []struct{ configured, stored string }{
+		{`to * by * read`, `{0}to *  by * read`},
+		{`to dn.exact="dc=Example,dc=com" by dn="cn=Admin, dc=example,dc=com" write`, `to dn.base="dc=example,dc=com" by dn.base="cn=admin,dc=example,dc=com" write`},
+		{`to dn.sub="ou=people,dc=example,dc=com" by * read stop`, `to dn.subtree="ou=people,dc=example,dc=com" by * read`},
+		{`TO attr=userPassword BY self WRITE`, `to attrs=userpassword by self write`},
+		{`to dn.regex="^cn=([^,]+),dc=Example$" by * read`, `to dn.regex="^cn=([^,]+),dc=Example$" by * read`},
+	} {
+		if a, b := normalizeOlcAccessRule(c.configured), normalizeOlcAccessRule(c.stored); a != b {
+			t.Errorf("Expected %q and %q to be equivalent, got %q and %q", c.configured, c.stored, a, b)
+		}
+	}
+	for _, c := range []struct{ a, b string }{
+		{`to * by * read`, `to * by * write`},
+		{`to * by * read`, `to * by * read continue`},
+		{`to dn.regex="^cn=a$" by * read`, `to dn.regex="^CN=a$" by * read`},
+		{`to dn.one="dc=example" by * read`, `to dn.subtree="dc=example" by * read`},
+	} {
+		if normalizeOlcAccessRule(c.a) == normalizeOlcAccessRule(c.b) {
+			t.Errorf("Expected %q and %q to differ", c.a, c.b)
+		}
+	}
+}
+
+func TestComputeOlcAccessDeltas(t *testing.T) {
+	changes := func(old, new []string) []string {
+		modify := ldap.NewModifyRequest("olcDatabase={1}mdb,cn=config", nil)
+		computeOlcAccessDeltas(modify, old, new)
+		result := []string{}
+		for _, c := range modify.Changes {
+			result = append(result, map[uint]string{ldap.AddAttribute: "add", ldap.DeleteAttribute: "delete"}[c.Operation])
+			result = append(result, c.Modification.Vals...)
+		}
+		return result
+	}
+	a, b, c := "to * by * read", "to attrs=userPassword by self write", "to * by users read"
+
+	for _, test := range []struct {
+		old, new, expected []string
+	}{
+		// unchanged, even if written differently
+		{[]string{a, b}, []string{"to *  by * read stop", b}, []string{}},
+		// appended
+		{[]string{a}, []string{a, b}, []string{"add", "{1}" + b}},
+		// removed from the end
+		{[]string{a, b}, []string{a}, []string{"delete", "{1}"}},
+		// changed in the middle: the following rules are deleted from the
+		// last one and added back
+		{[]string{a, b, c}, []string{a, c}, []string{"delete", "{2}", "delete", "{1}", "add", "{1}" + c}},
+	} {
+		if result := changes(test.old, test.new); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Unexpected changes from %q to %q: %q", test.old, test.new, result)
+		}
+	}
+}
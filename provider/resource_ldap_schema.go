@@ -0,0 +1,542 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/trevex/terraform-provider-ldap/util"
+)
+
+// the parent entry of all schema entries in OpenLDAP's cn=config
+const olcSchemaBaseDN = "cn=schema,cn=config"
+
+func resourceLDAPSchema() *schema.Resource {
+	stringList := func(description string, required bool) *schema.Schema {
+		return &schema.Schema{
+			Type:        schema.TypeList,
+			Description: description,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+			Required:    required,
+			Optional:    !required,
+		}
+	}
+	optionalString := func(description string) *schema.Schema {
+		return &schema.Schema{
+			Type:        schema.TypeString,
+			Description: description,
+			Optional:    true,
+		}
+	}
+	optionalBool := func(description string) *schema.Schema {
+		return &schema.Schema{
+			Type:        schema.TypeBool,
+			Description: description,
+			Optional:    true,
+		}
+	}
+
+	extensions := func(description string) *schema.Schema {
+		return &schema.Schema{
+			Type:        schema.TypeSet,
+			Description: description,
+			Optional:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Type:         schema.TypeString,
+						Description:  "The name of the extension, in upper case (e.g. `X-ORIGIN`).",
+						Required:     true,
+						ValidateFunc: validation.StringMatch(regexp.MustCompile(`^X-[A-Z0-9_-]+$`), "must start with X- and be in upper case"),
+					},
+					"values": stringList("The values of the extension.", true),
+				},
+			},
+		}
+	}
+
+	return &schema.Resource{
+		CreateContext: resourceLDAPSchemaCreate,
+		ReadContext:   resourceLDAPSchemaRead,
+		UpdateContext: resourceLDAPSchemaUpdate,
+		DeleteContext: resourceLDAPSchemaDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Read:   schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceLDAPSchemaImport,
+		},
+
+		Description: "The `ldap_schema`-resource manages a schema entry (`cn={N}name,cn=schema,cn=config`) in the configuration backend of OpenLDAP, holding custom attribute types and object classes.",
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the schema entry, without the `{N}` ordering prefix assigned by the server.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"dn": {
+				Type:        schema.TypeString,
+				Description: "The DN of the schema entry, including the ordering prefix assigned by the server.",
+				Computed:    true,
+			},
+			"attribute_type": {
+				Type:        schema.TypeList,
+				Description: "The attribute types defined by this schema entry, in order.",
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"oid":                  {Type: schema.TypeString, Description: "The OID of the attribute type.", Required: true},
+						"names":                stringList("The names of the attribute type.", true),
+						"description":          optionalString("The description of the attribute type."),
+						"obsolete":             optionalBool("Whether the attribute type is obsolete."),
+						"sup":                  optionalString("The attribute type this one is derived from."),
+						"syntax":               optionalString("The syntax OID, optionally followed by a length bound (e.g. `1.3.6.1.4.1.1466.115.121.1.15{256}`)."),
+						"equality":             optionalString("The equality matching rule."),
+						"ordering":             optionalString("The ordering matching rule."),
+						"substr":               optionalString("The substrings matching rule."),
+						"single_value":         optionalBool("Whether the attribute may only hold a single value."),
+						"collective":           optionalBool("Whether the attribute is collective."),
+						"no_user_modification": optionalBool("Whether the attribute cannot be modified by users."),
+						"usage": {
+							Type:         schema.TypeString,
+							Description:  "The usage of the attribute type: userApplications, directoryOperation, distributedOperation or dSAOperation.",
+							Optional:     true,
+							ValidateFunc: validation.StringInSlice([]string{"userApplications", "directoryOperation", "distributedOperation", "dSAOperation"}, false),
+						},
+						"extension": extensions("The extensions of the attribute type (e.g. `X-ORIGIN`)."),
+					},
+				},
+			},
+			"object_class": {
+				Type:        schema.TypeList,
+				Description: "The object classes defined by this schema entry, in order.",
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"oid":         {Type: schema.TypeString, Description: "The OID of the object class.", Required: true},
+						"names":       stringList("The names of the object class.", true),
+						"description": optionalString("The description of the object class."),
+						"obsolete":    optionalBool("Whether the object class is obsolete."),
+						"sup":         stringList("The superclasses of the object class.", false),
+						"kind": {
+							Type:         schema.TypeString,
+							Description:  "The kind of object class: STRUCTURAL, AUXILIARY or ABSTRACT.",
+							Optional:     true,
+							ValidateFunc: validation.StringInSlice([]string{"STRUCTURAL", "AUXILIARY", "ABSTRACT"}, false),
+						},
+						"must":      stringList("The attributes an entry of this class must have.", false),
+						"may":       stringList("The attributes an entry of this class may have.", false),
+						"extension": extensions("The extensions of the object class (e.g. `X-ORIGIN`)."),
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceLDAPSchemaImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	dn := d.Id()
+	debugLog("ldap_schema::import - going to import dn %q", dn)
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) != 1 {
+		return nil, fmt.Errorf("Invalid schema entry DN %q", dn)
+	}
+	_, name := splitOrderingPrefix(parsed.RDNs[0].Attributes[0].Value)
+	d.Set("name", name)
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceLDAPSchemaCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	name := d.Get("name").(string)
+	dn := fmt.Sprintf("cn=%s,%s", escapeDNValue(name), olcSchemaBaseDN)
+
+	debugLog("ldap_schema::create - creating schema entry %q", dn)
+
+	request := ldap.NewAddRequest(dn, []ldap.Control{})
+	request.Attribute("objectClass", []string{"olcSchemaConfig"})
+	request.Attribute("cn", []string{name})
+	if values := renderSchemaDefinitions(expandAttributeTypes(d.Get("attribute_type").([]interface{}))); len(values) > 0 {
+		request.Attribute("olcAttributeTypes", values)
+	}
+	if values := renderSchemaDefinitions(expandObjectClasses(d.Get("object_class").([]interface{}))); len(values) > 0 {
+		request.Attribute("olcObjectClasses", values)
+	}
+
+	if err := client.Add(ctx, request); err != nil {
+		return ldapDiagnostics(err, dn, nil)
+	}
+	client.ResetSchema()
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+
+	// the server inserts an ordering prefix into the RDN, so let's look up
+	// the actual DN of the entry we just created
+	actualDN, err := findSchemaEntryDN(ctx, client, name)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if actualDN == "" {
+		return diag.FromErr(fmt.Errorf("Schema entry %q not found after creation", name))
+	}
+
+	debugLog("ldap_schema::create - schema entry %q added as %q", name, actualDN)
+
+	d.SetId(actualDN)
+	return resourceLDAPSchemaRead(ctx, d, meta)
+}
+
+func resourceLDAPSchemaRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	dn := d.Id()
+
+	debugLog("ldap_schema::read - looking for schema entry %q", dn)
+
+	request := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectClass=olcSchemaConfig)",
+		[]string{"olcAttributeTypes", "olcObjectClasses"},
+		nil,
+	)
+
+	sr, err := client.Search(ctx, request)
+	if err != nil {
+		if err, ok := err.(*ldap.Error); ok {
+			if err.ResultCode == 32 { // no such object
+				warnLog("ldap_schema::read - schema entry not found, removing %q from state because it no longer exists in LDAP", dn)
+				d.SetId("")
+				return nil
+			}
+		}
+		debugLog("ldap_schema::read - lookup for %q returned an error %v", dn, err)
+		return diag.FromErr(err)
+	}
+	if len(sr.Entries) == 0 {
+		return diag.FromErr(fmt.Errorf("Entry %q is not a schema entry", dn))
+	}
+
+	attributeTypes, err := util.ParseSchemaDefinitions(sortByOrderingPrefix(sr.Entries[0].GetEqualFoldAttributeValues("olcAttributeTypes")))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	objectClasses, err := util.ParseSchemaDefinitions(sortByOrderingPrefix(sr.Entries[0].GetEqualFoldAttributeValues("olcObjectClasses")))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.Set("dn", dn)
+	if err := d.Set("attribute_type", flattenAttributeTypes(attributeTypes)); err != nil {
+		warnLog("ldap_schema::read - error setting attribute types for %q : %v", dn, err)
+		return diag.FromErr(err)
+	}
+	if err := d.Set("object_class", flattenObjectClasses(objectClasses)); err != nil {
+		warnLog("ldap_schema::read - error setting object classes for %q : %v", dn, err)
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceLDAPSchemaUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	dn := d.Id()
+
+	debugLog("ldap_schema::update - performing update on %q", dn)
+
+	modify := ldap.NewModifyRequest(dn, []ldap.Control{})
+
+	if d.HasChange("attribute_type") {
+		o, n := d.GetChange("attribute_type")
+		addSchemaDefinitionDeltas(modify, "olcAttributeTypes",
+			renderSchemaDefinitions(expandAttributeTypes(o.([]interface{}))),
+			renderSchemaDefinitions(expandAttributeTypes(n.([]interface{}))))
+	}
+	if d.HasChange("object_class") {
+		o, n := d.GetChange("object_class")
+		addSchemaDefinitionDeltas(modify, "olcObjectClasses",
+			renderSchemaDefinitions(expandObjectClasses(o.([]interface{}))),
+			renderSchemaDefinitions(expandObjectClasses(n.([]interface{}))))
+	}
+
+	if len(modify.Changes) > 0 {
+		err := client.Modify(ctx, modify)
+		if err != nil {
+			errorLog("ldap_schema::update - error modifying schema entry %q: %v", dn, err)
+			return ldapDiagnostics(err, dn, nil)
+		}
+		client.ResetSchema()
+	} else {
+		warnLog("ldap_schema::update - didn't actually make changes to %q because there were no changes requested", dn)
+	}
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	return resourceLDAPSchemaRead(ctx, d, meta)
+}
+
+func resourceLDAPSchemaDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*Client)
+	dn := d.Id()
+
+	debugLog("ldap_schema::delete - removing %q", dn)
+
+	// note that older releases of OpenLDAP refuse to delete schema entries
+	// with unwillingToPerform; the error is reported as is
+	err := client.Del(ctx, ldap.NewDelRequest(dn, nil))
+	if err != nil {
+		errorLog("ldap_schema::delete - error removing %q: %v", dn, err)
+		return ldapDiagnostics(err, dn, nil)
+	}
+	client.ResetSchema()
+	if client.DryRun() {
+		return dryRunDiagnostics(d)
+	}
+	debugLog("ldap_schema::delete - %q removed", dn)
+	return nil
+}
+
+// addSchemaDefinitionDeltas adds the changes to go from the old to the new
+// list of definitions; if definitions are only appended, they are added to
+// the entry, which every release of cn=config supports, otherwise the whole
+// list is replaced in place.
+func addSchemaDefinitionDeltas(modify *ldap.ModifyRequest, attribute string, old, new []string) {
+	if len(new) >= len(old) {
+		appended := true
+		for i := range old {
+			if old[i] != new[i] {
+				appended = false
+				break
+			}
+		}
+		if appended {
+			values := []string{}
+			for i := len(old); i < len(new); i++ {
+				values = append(values, fmt.Sprintf("{%d}%s", i, new[i]))
+			}
+			debugLog("ldap_schema::deltas - appending %d values to %q", len(values), attribute)
+			modify.Add(attribute, values)
+			return
+		}
+	}
+	debugLog("ldap_schema::deltas - replacing values of %q", attribute)
+	modify.Replace(attribute, new)
+}
+
+// findSchemaEntryDN looks up the DN of the schema entry with the given name,
+// regardless of the ordering prefix; an empty DN is returned if there is no
+// such entry.
+func findSchemaEntryDN(ctx context.Context, client *Client, name string) (string, error) {
+	request := ldap.NewSearchRequest(
+		olcSchemaBaseDN,
+		ldap.ScopeSingleLevel,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(objectClass=olcSchemaConfig)",
+		[]string{"cn"},
+		nil,
+	)
+
+	sr, err := client.Search(ctx, request)
+	if err != nil {
+		debugLog("ldap_schema - lookup of schema entries returned an error %v", err)
+		return "", err
+	}
+	for _, entry := range sr.Entries {
+		for _, cn := range entry.GetEqualFoldAttributeValues("cn") {
+			if _, n := splitOrderingPrefix(cn); strings.EqualFold(n, name) {
+				return entry.DN, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+var orderingPrefixRegexp = regexp.MustCompile(`^\{(\d+)\}`)
+
+// splitOrderingPrefix splits the `{N}` ordering prefix that cn=config
+// prepends to the values of ordered attributes (and to RDNs) from the actual
+// value; an index of -1 is returned if the value has no prefix.
+func splitOrderingPrefix(value string) (int, string) {
+	m := orderingPrefixRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return -1, value
+	}
+	index, err := strconv.Atoi(m[1])
+	if err != nil {
+		return -1, value
+	}
+	return index, value[len(m[0]):]
+}
+
+// sortByOrderingPrefix sorts the values of an ordered attribute by their
+// index and strips the ordering prefixes.
+func sortByOrderingPrefix(values []string) []string {
+	type indexed struct {
+		index int
+		value string
+	}
+	sorted := make([]indexed, 0, len(values))
+	for i, v := range values {
+		index, value := splitOrderingPrefix(v)
+		if index < 0 {
+			index = i
+		}
+		sorted = append(sorted, indexed{index, value})
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].index < sorted[j].index
+	})
+	result := make([]string, 0, len(sorted))
+	for _, s := range sorted {
+		result = append(result, s.value)
+	}
+	return result
+}
+
+func renderSchemaDefinitions(definitions []*util.SchemaDefinition) []string {
+	values := make([]string, 0, len(definitions))
+	for _, d := range definitions {
+		values = append(values, d.String())
+	}
+	return values
+}
+
+func expandStringList(v interface{}) []string {
+	list := []string{}
+	for _, s := range v.([]interface{}) {
+		list = append(list, s.(string))
+	}
+	return list
+}
+
+func expandAttributeTypes(blocks []interface{}) []*util.SchemaDefinition {
+	definitions := make([]*util.SchemaDefinition, 0, len(blocks))
+	for _, b := range blocks {
+		m := b.(map[string]interface{})
+		d := &util.SchemaDefinition{
+			OID:                m["oid"].(string),
+			Names:              expandStringList(m["names"]),
+			Description:        m["description"].(string),
+			Obsolete:           m["obsolete"].(bool),
+			Syntax:             m["syntax"].(string),
+			Equality:           m["equality"].(string),
+			Ordering:           m["ordering"].(string),
+			Substr:             m["substr"].(string),
+			SingleValue:        m["single_value"].(bool),
+			Collective:         m["collective"].(bool),
+			NoUserModification: m["no_user_modification"].(bool),
+			Usage:              m["usage"].(string),
+			Extensions:         expandSchemaExtensions(m["extension"].(*schema.Set)),
+		}
+		if sup := m["sup"].(string); sup != "" {
+			d.Sup = []string{sup}
+		}
+		definitions = append(definitions, d)
+	}
+	return definitions
+}
+
+func flattenAttributeTypes(definitions []*util.SchemaDefinition) []interface{} {
+	blocks := make([]interface{}, 0, len(definitions))
+	for _, d := range definitions {
+		sup := ""
+		if len(d.Sup) > 0 {
+			sup = d.Sup[0]
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"oid":                  d.OID,
+			"names":                d.Names,
+			"description":          d.Description,
+			"obsolete":             d.Obsolete,
+			"sup":                  sup,
+			"syntax":               d.Syntax,
+			"equality":             d.Equality,
+			"ordering":             d.Ordering,
+			"substr":               d.Substr,
+			"single_value":         d.SingleValue,
+			"collective":           d.Collective,
+			"no_user_modification": d.NoUserModification,
+			"usage":                d.Usage,
+			"extension":            flattenSchemaExtensions(d.Extensions),
+		})
+	}
+	return blocks
+}
+
+func expandObjectClasses(blocks []interface{}) []*util.SchemaDefinition {
+	definitions := make([]*util.SchemaDefinition, 0, len(blocks))
+	for _, b := range blocks {
+		m := b.(map[string]interface{})
+		definitions = append(definitions, &util.SchemaDefinition{
+			OID:         m["oid"].(string),
+			Names:       expandStringList(m["names"]),
+			Description: m["description"].(string),
+			Obsolete:    m["obsolete"].(bool),
+			Sup:         expandStringList(m["sup"]),
+			Kind:        m["kind"].(string),
+			Must:        expandStringList(m["must"]),
+			May:         expandStringList(m["may"]),
+			Extensions:  expandSchemaExtensions(m["extension"].(*schema.Set)),
+		})
+	}
+	return definitions
+}
+
+func flattenObjectClasses(definitions []*util.SchemaDefinition) []interface{} {
+	blocks := make([]interface{}, 0, len(definitions))
+	for _, d := range definitions {
+		blocks = append(blocks, map[string]interface{}{
+			"oid":         d.OID,
+			"names":       d.Names,
+			"description": d.Description,
+			"obsolete":    d.Obsolete,
+			"sup":         d.Sup,
+			"kind":        d.Kind,
+			"must":        d.Must,
+			"may":         d.May,
+			"extension":   flattenSchemaExtensions(d.Extensions),
+		})
+	}
+	return blocks
+}
+
+func expandSchemaExtensions(set *schema.Set) map[string][]string {
+	extensions := map[string][]string{}
+	for _, e := range set.List() {
+		m := e.(map[string]interface{})
+		extensions[m["name"].(string)] = expandStringList(m["values"])
+	}
+	return extensions
+}
+
+func flattenSchemaExtensions(extensions map[string][]string) []interface{} {
+	blocks := make([]interface{}, 0, len(extensions))
+	for name, values := range extensions {
+		blocks = append(blocks, map[string]interface{}{
+			"name":   name,
+			"values": values,
+		})
+	}
+	return blocks
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLDAPSchemaLifecycle(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	s.PutEntry("cn=config", map[string][]string{"objectClass": {"olcGlobal"}, "cn": {"config"}})
+	s.PutEntry(olcSchemaBaseDN, map[string][]string{"objectClass": {"olcSchemaConfig"}, "cn": {"schema"}})
+	meta := testProviderMeta(t, s)
+	r := resourceLDAPSchema()
+	dn := "cn=custom," + olcSchemaBaseDN
+
+	attributeType := func(oid, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"oid":         oid,
+			"names":       []interface{}{name},
+			"description": "A (code) $ value",
+			"syntax":      "1.3.6.1.4.1.1466.115.121.1.15",
+			"collective":  true,
+			"extension": []interface{}{
+				map[string]interface{}{"name": "X-ORIGIN", "values": []interface{}{"user defined"}},
+			},
+		}
+	}
+	config := map[string]interface{}{
+		"name":           "custom",
+		"attribute_type": []interface{}{attributeType("1.3.6.1.4.1.99999.1.1", "customCode")},
+		"object_class": []interface{}{
+			map[string]interface{}{
+				"oid":   "1.3.6.1.4.1.99999.2.1",
+				"names": []interface{}{"customObject"},
+				"kind":  "AUXILIARY",
+				"may":   []interface{}{"customCode"},
+				"extension": []interface{}{
+					map[string]interface{}{"name": "X-ORIGIN", "values": []interface{}{"user defined", "example"}},
+				},
+			},
+		},
+	}
+
+	// the definitions round-trip, including COLLECTIVE and extensions
+	state := testApply(t, r, nil, config, meta)
+	if state.ID != dn {
+		t.Errorf("Unexpected ID %q", state.ID)
+	}
+	values := s.Values(dn, "olcAttributeTypes")
+	if len(values) != 1 || !strings.Contains(values[0], "COLLECTIVE") || !strings.Contains(values[0], "X-ORIGIN 'user defined'") {
+		t.Errorf("Unexpected attribute types %v", values)
+	}
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes after create")
+	}
+
+	// appended definitions are added
+	config["attribute_type"] = append(config["attribute_type"].([]interface{}), attributeType("1.3.6.1.4.1.99999.1.2", "customLabel"))
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "olcAttributeTypes"); len(values) != 2 || !strings.HasPrefix(values[1], "{1}") {
+		t.Errorf("Unexpected attribute types %v", values)
+	}
+	if !testPlanIsEmpty(t, r, state, config, meta) {
+		t.Errorf("Expected no changes after appending")
+	}
+
+	// other changes replace the definitions
+	config["attribute_type"].([]interface{})[0].(map[string]interface{})["description"] = "A code"
+	state = testApply(t, r, state, config, meta)
+	if values := s.Values(dn, "olcAttributeTypes"); len(values) != 2 || !strings.Contains(values[0], "DESC 'A code'") {
+		t.Errorf("Unexpected attribute types %v", values)
+	}
+
+	imported := testImport(t, r, dn, meta)
+	if !testPlanIsEmpty(t, r, imported, config, meta) {
+		t.Errorf("Expected no changes after import")
+	}
+
+	testDestroy(t, r, state, meta)
+	if s.Entry(dn) != nil {
+		t.Errorf("Expected %q to be deleted", dn)
+	}
+}
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// sensitiveAttributesSchema describes the attributes whose values are
+// hidden in plans and, since they are usually write-only or hashed by the
+// server (e.g. userPassword, unicodePwd, sambaNTPassword, krbPrincipalKey),
+// never read back: their values in state are the ones last written.
+func sensitiveAttributesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeSet,
+		Description: "The map of sensitive attributes of this object, e.g. passwords; their values are hidden in plans and, as they are usually write-only or hashed by the server, they are written as a whole but never read back, so they don't cause drift.",
+		Set:         attributeHash,
+		Sensitive:   true,
+		Optional:    true,
+
+		Elem: &schema.Schema{
+			Type:        schema.TypeMap,
+			Description: "The list of values for a given attribute.",
+			MinItems:    1,
+			MaxItems:    1,
+			Elem: &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The individual value for the given attribute.",
+			},
+		},
+	}
+}
+
+// attributeSetValues collects the values of a set of single-entry attribute
+// maps by attribute name, encoding them as they are sent to the server.
+func attributeSetValues(set *schema.Set) map[string][]string {
+	m := make(map[string][]string)
+	for _, attribute := range set.List() {
+		for name, value := range attribute.(map[string]interface{}) {
+			m[name] = append(m[name], toAttributeValue(name, value.(string)))
+		}
+	}
+	return m
+}
+
+// attributeSetNames returns the names of the attributes in the set.
+func attributeSetNames(set *schema.Set) []string {
+	names := []string{}
+	for name := range attributeSetValues(set) {
+		names = append(names, name)
+	}
+	return names
+}
+
+// sensitiveAttributesKey is the key of the names of the sensitive attributes
+// of a resource in the context of its requests.
+type sensitiveAttributesKey struct{}
+
+// withSensitiveAttributes returns the context to issue the write requests of
+// a resource with, so that the values of its sensitive attributes, before and
+// after the change, are redacted from the LDIF output.
+func withSensitiveAttributes(ctx context.Context, d *schema.ResourceData) context.Context {
+	o, n := d.GetChange("sensitive_attributes")
+	names := append(attributeSetNames(o.(*schema.Set)), attributeSetNames(n.(*schema.Set))...)
+	return context.WithValue(ctx, sensitiveAttributesKey{}, names)
+}
+
+// sensitiveAttributeNames returns the names of the sensitive attributes of
+// the context.
+func sensitiveAttributeNames(ctx context.Context) []string {
+	names, _ := ctx.Value(sensitiveAttributesKey{}).([]string)
+	return names
+}
+
+// addSensitiveAttributeDeltas replaces the sensitive attributes whose values
+// changed and removes the ones that are gone; values are never compared with
+// the server, only with the ones written before.
+func addSensitiveAttributeDeltas(modify *ldap.ModifyRequest, os, ns *schema.Set) {
+	oldValues := attributeSetValues(os)
+	newValues := attributeSetValues(ns)
+	for name, values := range newValues {
+		if old, ok := oldValues[name]; ok && equalStringSets(old, values) {
+			continue
+		}
+		debugLog("ldap_object::deltas - replacing sensitive attribute %q", name)
+		modify.Replace(name, values)
+	}
+	for name := range oldValues {
+		if _, ok := newValues[name]; !ok {
+			// replacing without values removes the attribute, and doesn't
+			// fail if it is not there
+			debugLog("ldap_object::deltas - dropping sensitive attribute %q", name)
+			modify.Replace(name, []string{})
+		}
+	}
+}
+
+// addSensitiveValueDeltas adds the values of the sensitive attributes which
+// are new and deletes the ones that are gone, leaving the other values of the
+// attributes alone; values are never compared with the server, only with the
+// ones written before.
+func addSensitiveValueDeltas(modify *ldap.ModifyRequest, os, ns *schema.Set) {
+	oldValues := attributeSetValues(os)
+	newValues := attributeSetValues(ns)
+	for name, values := range oldValues {
+		if removed := missingValues(values, newValues[name]); len(removed) > 0 {
+			debugLog("ldap_object_attributes::deltas - deleting values of sensitive attribute %q", name)
+			modify.Delete(name, removed)
+		}
+	}
+	for name, values := range newValues {
+		if added := missingValues(values, oldValues[name]); len(added) > 0 {
+			debugLog("ldap_object_attributes::deltas - adding values of sensitive attribute %q", name)
+			modify.Add(name, added)
+		}
+	}
+}
+
+// missingValues returns the values which are not in others.
+func missingValues(values, others []string) []string {
+	known := map[string]bool{}
+	for _, v := range others {
+		known[v] = true
+	}
+	missing := []string{}
+	for _, v := range values {
+		if !known[v] {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+// equalStringSets checks whether both slices hold the same values in any
+// order.
+func equalStringSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := map[string]int{}
+	for _, v := range a {
+		counts[v]++
+	}
+	for _, v := range b {
+		if counts[v] == 0 {
+			return false
+		}
+		counts[v]--
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"strings"
+)
+
+// the operational and server-generated attributes that can't be set by
+// clients, but that some servers (e.g. Active Directory) return among the
+// user attributes
+var serverManagedAttributes = []string{
+	// RFC 4512 and OpenLDAP operational attributes
+	"createTimestamp",
+	"creatorsName",
+	"modifyTimestamp",
+	"modifiersName",
+	"entryDN",
+	"entryUUID",
+	"entryCSN",
+	"structuralObjectClass",
+	"subschemaSubentry",
+	"hasSubordinates",
+	"numSubordinates",
+	"pwdChangedTime",
+	"pwdFailureTime",
+	"pwdAccountLockedTime",
+	// Active Directory
+	"distinguishedName",
+	"name",
+	"instanceType",
+	"objectCategory",
+	"objectGUID",
+	"objectSid",
+	"whenCreated",
+	"whenChanged",
+	"uSNCreated",
+	"uSNChanged",
+	"dSCorePropagationData",
+	"isCriticalSystemObject",
+	"memberOf",
+	"msDS-parentdistname",
+}
+
+// the server-generated attributes of the entries of some object classes
+var serverManagedClassAttributes = map[string][]string{
+	"user": {
+		"badPasswordTime",
+		"badPwdCount",
+		"lastLogoff",
+		"lastLogon",
+		"lastLogonTimestamp",
+		"logonCount",
+		"pwdLastSet",
+		"sAMAccountType",
+	},
+	"group": {
+		"sAMAccountType",
+	},
+}
+
+// isServerManagedAttribute checks whether the attribute is an operational or
+// server-generated one, either in general or for any of the object classes.
+func isServerManagedAttribute(name string, objectClasses []string) bool {
+	if containsEqualFold(serverManagedAttributes, name) {
+		return true
+	}
+	for _, oc := range objectClasses {
+		if containsEqualFold(serverManagedClassAttributes[strings.ToLower(oc)], name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// the OIDs of LDAP transactions (RFC 5805)
+const (
+	startTransactionOID   = "1.3.6.1.1.21.1"
+	transactionControlOID = "1.3.6.1.1.21.2"
+	endTransactionOID     = "1.3.6.1.1.21.3"
+)
+
+// transactionKey is the key of the transaction identifier in the context of
+// the requests that are part of a transaction.
+type transactionKey struct{}
+
+// EnableTransactions makes the client group the requests of the operations
+// that change several entries in transactions, if the server supports them;
+// it tells whether it does.
+func (c *Client) EnableTransactions(ctx context.Context) bool {
+	rootDSE, err := readRootDSE(ctx, c, "supportedExtension", "supportedControl")
+	if err != nil {
+		warnLog("transactions - unable to read the root DSE, transactions are disabled: %v", err)
+		return false
+	}
+	if !containsEqualFold(rootDSE.GetEqualFoldAttributeValues("supportedExtension"), startTransactionOID) ||
+		!containsEqualFold(rootDSE.GetEqualFoldAttributeValues("supportedExtension"), endTransactionOID) ||
+		!containsEqualFold(rootDSE.GetEqualFoldAttributeValues("supportedControl"), transactionControlOID) {
+		warnLog("transactions - the server does not support transactions, they are disabled")
+		return false
+	}
+	c.transactions = true
+	return true
+}
+
+// StartTransaction starts a transaction, if they are enabled, and returns the
+// context to issue the requests that are part of it with, along with the
+// function ending it, which commits the transaction if told so and aborts it
+// otherwise. Without a transaction, or within one already, the context is
+// returned as it is and ending it does nothing.
+func (c *Client) StartTransaction(ctx context.Context) (context.Context, func(commit bool) error, error) {
+	noop := func(bool) error { return nil }
+	if !c.transactions || c.dryRun || ctx.Value(transactionKey{}) != nil {
+		return ctx, noop, nil
+	}
+
+	response, err := c.Extended(ctx, ldap.NewExtendedRequest(startTransactionOID, nil))
+	if err != nil {
+		return ctx, noop, errors.Wrap(err, "Starting a transaction")
+	}
+	// the response has a value but no name, see Extended
+	id := response.Name
+	if response.Value != nil {
+		id = response.Value.Data.String()
+	}
+	if id == "" {
+		return ctx, noop, errors.New("Starting a transaction: the server returned no transaction identifier")
+	}
+	debugLog("transactions - started %x", id)
+
+	end := func(commit bool) error {
+		value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "txnEndReq")
+		if !commit {
+			value.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, false, "commit"))
+		}
+		value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, id, "identifier"))
+		request := ldap.NewExtendedRequest(endTransactionOID, ber.NewString(ber.ClassContext, ber.TypePrimitive, 1, string(value.Bytes()), "requestValue"))
+
+		// the transaction is aborted even if the operation was cancelled
+		endCtx := ctx
+		if !commit {
+			endCtx = context.Background()
+		}
+		// successful responses have neither a name nor a value
+		_, err := c.Extended(endCtx, request)
+		if c.isEmptyExtendedResponse(err) {
+			err = nil
+		}
+		if err != nil {
+			if commit {
+				return errors.Wrap(err, "Committing the transaction")
+			}
+			warnLog("transactions - unable to abort %x: %v", id, err)
+			return err
+		}
+		debugLog("transactions - ended %x (commit: %v)", id, commit)
+		return nil
+	}
+	return context.WithValue(ctx, transactionKey{}, id), end, nil
+}
+
+// deleteExistingEntry deletes an entry unless it is gone already, telling
+// whether it existed. Within a transaction, a missing entry may only be
+// reported when committing it, failing the whole transaction, so the entry is
+// looked up first.
+func deleteExistingEntry(ctx context.Context, client *Client, dn string) (bool, error) {
+	if ctx.Value(transactionKey{}) != nil {
+		request := ldap.NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false, "(objectClass=*)", []string{"1.1"}, nil)
+		if _, err := client.Search(ctx, request); err != nil {
+			if isLDAPResultCode(err, ldap.LDAPResultNoSuchObject) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	err := client.Del(ctx, ldap.NewDelRequest(dn, nil))
+	if isLDAPResultCode(err, ldap.LDAPResultNoSuchObject) {
+		return false, nil
+	}
+	return true, err
+}
+
+// transactionControls returns the control making a request part of the
+// transaction of the context, if any.
+func transactionControls(ctx context.Context) []ldap.Control {
+	id, ok := ctx.Value(transactionKey{}).(string)
+	if !ok {
+		return nil
+	}
+	return []ldap.Control{ldap.NewControlString(transactionControlOID, true, id)}
+}
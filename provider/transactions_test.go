@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/pkg/errors"
+)
+
+func TestLDAPEntriesTransaction(t *testing.T) {
+	ctx := context.Background()
+	r := resourceLDAPEntries()
+	config := func(dns ...string) map[string]interface{} {
+		entries := []interface{}{}
+		for _, dn := range dns {
+			entries = append(entries, map[string]interface{}{
+				"dn":             dn,
+				"object_classes": []interface{}{"organizationalUnit"},
+			})
+		}
+		return map[string]interface{}{
+			"base_dn": testBaseDN,
+			"entry":   entries,
+		}
+	}
+	people, missing := "ou=people,"+testBaseDN, "ou=staff,ou=missing,"+testBaseDN
+
+	// without support, the entries are added one by one
+	s := newTestLDAPServer(t, testBaseDN)
+	meta := testProviderMeta(t, s)
+	if meta.(*Client).EnableTransactions(ctx) {
+		t.Fatalf("Expected transactions not to be supported")
+	}
+	diff, err := r.Diff(ctx, nil, terraform.NewResourceConfigRaw(config(people, missing)), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	if _, diags := r.Apply(ctx, nil, diff, meta); !diags.HasError() {
+		t.Fatalf("Expected adding %q to fail", missing)
+	}
+	if s.Entry(people) == nil {
+		t.Errorf("Expected %q to be added without a transaction", people)
+	}
+
+	// with transactions, either all entries are added or none
+	s = newTestLDAPServer(t, testBaseDN)
+	s.EnableTransactions()
+	meta = testProviderMeta(t, s)
+	if !meta.(*Client).EnableTransactions(ctx) {
+		t.Fatalf("Expected transactions to be supported")
+	}
+	if _, diags := r.Apply(ctx, nil, diff, meta); !diags.HasError() {
+		t.Fatalf("Expected adding %q to fail", missing)
+	}
+	if s.Entry(people) != nil {
+		t.Errorf("Expected %q not to be added by the failed transaction", people)
+	}
+
+	state := testApply(t, r, nil, config(people, "ou=staff,"+people), meta)
+	if s.Entry(people) == nil || s.Entry("ou=staff,"+people) == nil {
+		t.Errorf("Expected the entries to be added by the transaction")
+	}
+	testDestroy(t, r, state, meta)
+	if s.Entry(people) != nil || s.Entry("ou=staff,"+people) != nil {
+		t.Errorf("Expected the entries to be deleted by the transaction")
+	}
+}
+
+func TestLDAPLDIFTransaction(t *testing.T) {
+	ctx := context.Background()
+	r := resourceLDAPLDIF()
+	s := newTestLDAPServer(t, testBaseDN)
+	s.EnableTransactions()
+	meta := testProviderMeta(t, s)
+	if !meta.(*Client).EnableTransactions(ctx) {
+		t.Fatalf("Expected transactions to be supported")
+	}
+	people, staff := "ou=people,"+testBaseDN, "ou=staff,ou=people,"+testBaseDN
+	content := `dn: ` + people + `
+objectClass: organizationalUnit
+ou: people
+
+dn: ` + staff + `
+objectClass: organizationalUnit
+ou: staff
+`
+
+	// entries deleted by other means do not fail the transaction deleting
+	// the others
+	state := testApply(t, r, nil, map[string]interface{}{"content": content}, meta)
+	s.DeleteEntry(staff)
+	testDestroy(t, r, state, meta)
+	if s.Entry(people) != nil {
+		t.Errorf("Expected %q to be deleted", people)
+	}
+
+	// existing entries can only be adopted without a transaction
+	s.PutEntry(people, map[string][]string{"objectClass": {"organizationalUnit"}, "ou": {"people"}})
+	state = testApply(t, r, nil, map[string]interface{}{"content": content, "adopt_existing": true}, meta)
+	if s.Entry(staff) == nil {
+		t.Errorf("Expected %q to be added next to the adopted entry", staff)
+	}
+	testDestroy(t, r, state, meta)
+	if s.Entry(people) == nil || s.Entry(staff) != nil {
+		t.Errorf("Expected only the added entry to be deleted")
+	}
+}
+
+func TestLDAPObjectGroupMembersTransaction(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLDAPServer(t, testBaseDN)
+	s.EnableTransactions()
+	meta := testProviderMeta(t, s)
+	if !meta.(*Client).EnableTransactions(ctx) {
+		t.Fatalf("Expected transactions to be supported")
+	}
+	group, jdoe, bob := "cn=admins,"+testBaseDN, "cn=jdoe,"+testBaseDN, "cn=bob,"+testBaseDN
+
+	// the group and its members are each changed by a single request, which
+	// is atomic without a transaction
+	r := resourceLDAPObject()
+	config := map[string]interface{}{
+		"dn":                  group,
+		"object_classes":      []interface{}{"groupOfNames"},
+		"assert_prior_values": true,
+		"attributes": testAttributesConfig(map[string][]string{
+			"description": {"a"},
+			"member":      {jdoe},
+		}),
+	}
+	state := testApply(t, r, nil, config, meta)
+	members := resourceLDAPObjectAttributes()
+	membersState := testApply(t, members, nil, map[string]interface{}{
+		"dn":         group,
+		"attributes": testAttributesConfig(map[string][]string{"member": {bob}}),
+	}, meta)
+	if values := s.Values(group, "member"); !testSameValues(values, []string{jdoe, bob}) {
+		t.Errorf("Unexpected members %v", values)
+	}
+
+	// a failed change of the group leaves its members alone
+	config["attributes"] = testAttributesConfig(map[string][]string{
+		"description": {"b"},
+		"member":      {bob},
+	})
+	state = testRefresh(t, r, state, meta)
+	diff, err := r.Diff(ctx, state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatalf("Unable to plan: %v", err)
+	}
+	s.SetAttribute(group, "description", "changed")
+	if _, diags := r.Apply(ctx, state, diff, meta); !diags.HasError() {
+		t.Fatalf("Expected the change of the group to fail")
+	}
+	if values := s.Values(group, "member"); !testSameValues(values, []string{jdoe, bob}) {
+		t.Errorf("Expected the members to be left alone, got %v", values)
+	}
+
+	testDestroy(t, members, membersState, meta)
+	if values := s.Values(group, "member"); !testSameValues(values, []string{jdoe}) {
+		t.Errorf("Unexpected members %v", values)
+	}
+}
+
+func TestIsEmptyExtendedResponse(t *testing.T) {
+	s := newTestLDAPServer(t, testBaseDN)
+	client := testProviderMeta(t, s).(*Client)
+
+	if !client.isEmptyExtendedResponse(fmt.Errorf("ldap: malformed extended response: expected 4 children, got 2")) {
+		t.Errorf("Expected a decoding error to be taken for an empty response")
+	}
+	for _, err := range []error{
+		nil,
+		ldap.NewError(ldap.LDAPResultUnwillingToPerform, fmt.Errorf("unwilling")),
+		errors.Wrap(ldap.NewError(ldap.ErrorNetwork, fmt.Errorf("timed out")), "Committing"),
+		context.DeadlineExceeded,
+		ldap.ErrNilConnection,
+	} {
+		if client.isEmptyExtendedResponse(err) {
+			t.Errorf("Expected %v not to be taken for an empty response", err)
+		}
+	}
+}
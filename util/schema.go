@@ -0,0 +1,344 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaDefinition represents a single RFC 4512 schema definition, i.e. the
+// value of an attributeTypes, objectClasses, ldapSyntaxes or matchingRules
+// attribute of a subschema entry. Fields that do not apply to a given kind of
+// definition are left empty.
+type SchemaDefinition struct {
+	OID                string
+	Names              []string
+	Description        string
+	Obsolete           bool
+	Sup                []string
+	Kind               string // STRUCTURAL, AUXILIARY or ABSTRACT (object classes only)
+	Must               []string
+	May                []string
+	Syntax             string
+	Equality           string
+	Ordering           string
+	Substr             string
+	SingleValue        bool
+	Collective         bool
+	NoUserModification bool
+	Usage              string
+	Extensions         map[string][]string
+}
+
+// Name returns the primary name of the definition, falling back to its OID.
+func (d *SchemaDefinition) Name() string {
+	if len(d.Names) > 0 {
+		return d.Names[0]
+	}
+	return d.OID
+}
+
+// HasName checks whether the definition is known under the given name or OID;
+// the comparison is case insensitive, as are attribute and class names.
+func (d *SchemaDefinition) HasName(name string) bool {
+	if strings.EqualFold(d.OID, name) {
+		return true
+	}
+	for _, n := range d.Names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema is the parsed content of a subschema entry.
+type Schema struct {
+	AttributeTypes []*SchemaDefinition
+	ObjectClasses  []*SchemaDefinition
+	LDAPSyntaxes   []*SchemaDefinition
+	MatchingRules  []*SchemaDefinition
+}
+
+// AttributeType looks up an attribute type by name or OID, returning nil if
+// it is not part of the schema.
+func (s *Schema) AttributeType(name string) *SchemaDefinition {
+	return findDefinition(s.AttributeTypes, name)
+}
+
+// ObjectClass looks up an object class by name or OID, returning nil if it is
+// not part of the schema.
+func (s *Schema) ObjectClass(name string) *SchemaDefinition {
+	return findDefinition(s.ObjectClasses, name)
+}
+
+func findDefinition(definitions []*SchemaDefinition, name string) *SchemaDefinition {
+	for _, d := range definitions {
+		if d.HasName(name) {
+			return d
+		}
+	}
+	return nil
+}
+
+// ParseSchemaDefinitions parses a list of RFC 4512 definitions, as returned
+// by the server for one of the subschema attributes.
+func ParseSchemaDefinitions(values []string) ([]*SchemaDefinition, error) {
+	definitions := make([]*SchemaDefinition, 0, len(values))
+	for _, value := range values {
+		d, err := ParseSchemaDefinition(value)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, d)
+	}
+	return definitions, nil
+}
+
+// ParseSchemaDefinition parses a single RFC 4512 definition such as
+// "( 2.5.4.3 NAME ( 'cn' 'commonName' ) SUP name )".
+func ParseSchemaDefinition(value string) (*SchemaDefinition, error) {
+	tokens, err := tokenizeSchemaDefinition(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) < 3 || !tokens[0].is("(") || !tokens[len(tokens)-1].is(")") {
+		return nil, fmt.Errorf("invalid schema definition %q: expected a parenthesized description", value)
+	}
+	tokens = tokens[1 : len(tokens)-1]
+
+	d := &SchemaDefinition{
+		OID:        tokens[0].value,
+		Extensions: map[string][]string{},
+	}
+	p := &schemaParser{tokens: tokens, pos: 1}
+	for !p.done() {
+		keyword := strings.ToUpper(p.next().value)
+		switch keyword {
+		case "NAME":
+			d.Names, err = p.list()
+		case "DESC":
+			d.Description, err = p.single()
+		case "OBSOLETE":
+			d.Obsolete = true
+		case "SUP":
+			d.Sup, err = p.list()
+		case "STRUCTURAL", "AUXILIARY", "ABSTRACT":
+			d.Kind = keyword
+		case "MUST":
+			d.Must, err = p.list()
+		case "MAY":
+			d.May, err = p.list()
+		case "SYNTAX":
+			d.Syntax, err = p.single()
+		case "EQUALITY":
+			d.Equality, err = p.single()
+		case "ORDERING":
+			d.Ordering, err = p.single()
+		case "SUBSTR":
+			d.Substr, err = p.single()
+		case "SINGLE-VALUE":
+			d.SingleValue = true
+		case "COLLECTIVE":
+			d.Collective = true
+		case "NO-USER-MODIFICATION":
+			d.NoUserModification = true
+		case "USAGE":
+			d.Usage, err = p.single()
+		default:
+			if !strings.HasPrefix(keyword, "X-") {
+				return nil, fmt.Errorf("invalid schema definition %q: unknown keyword %q", value, keyword)
+			}
+			d.Extensions[keyword], err = p.list()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema definition %q: %v", value, err)
+		}
+	}
+	return d, nil
+}
+
+// schemaToken is a token of a definition; quoted strings are never syntax,
+// even if they read like it.
+type schemaToken struct {
+	value  string
+	quoted bool
+}
+
+// is checks whether the token is the given piece of syntax.
+func (t schemaToken) is(syntax string) bool {
+	return !t.quoted && t.value == syntax
+}
+
+type schemaParser struct {
+	tokens []schemaToken
+	pos    int
+}
+
+func (p *schemaParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *schemaParser) next() schemaToken {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+// single reads the value of a keyword taking exactly one argument.
+func (p *schemaParser) single() (string, error) {
+	if p.done() {
+		return "", fmt.Errorf("missing value at end of definition")
+	}
+	v := p.next()
+	if v.is("(") || v.is(")") {
+		return "", fmt.Errorf("unexpected %q", v.value)
+	}
+	return v.value, nil
+}
+
+// list reads the value of a keyword taking either one argument or a
+// parenthesized list of arguments, optionally separated by "$".
+func (p *schemaParser) list() ([]string, error) {
+	if p.done() {
+		return nil, fmt.Errorf("missing value at end of definition")
+	}
+	if !p.tokens[p.pos].is("(") {
+		v, err := p.single()
+		return []string{v}, err
+	}
+	p.pos++
+	values := []string{}
+	for !p.done() {
+		t := p.next()
+		switch {
+		case t.is(")"):
+			return values, nil
+		case t.is("$"):
+			continue
+		case t.is("("):
+			return nil, fmt.Errorf("unexpected nested list")
+		default:
+			values = append(values, t.value)
+		}
+	}
+	return nil, fmt.Errorf("unterminated list")
+}
+
+// tokenizeSchemaDefinition splits a definition into parentheses, "$"
+// separators, quoted strings (with their quotes removed and RFC 4512 escapes
+// resolved) and bare words.
+func tokenizeSchemaDefinition(value string) ([]schemaToken, error) {
+	tokens := []schemaToken{}
+	for i := 0; i < len(value); {
+		c := value[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '$':
+			tokens = append(tokens, schemaToken{value: string(c)})
+			i++
+		case c == '\'':
+			end := strings.IndexByte(value[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid schema definition %q: unterminated quoted string", value)
+			}
+			tokens = append(tokens, schemaToken{value: unescapeSchemaString(value[i+1 : i+1+end]), quoted: true})
+			i += end + 2
+		default:
+			j := i
+			for j < len(value) && !strings.ContainsRune(" \t\n\r()$'", rune(value[j])) {
+				j++
+			}
+			tokens = append(tokens, schemaToken{value: value[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+var schemaStringUnescaper = strings.NewReplacer(`\27`, `'`, `\5C`, `\`, `\5c`, `\`)
+
+func unescapeSchemaString(s string) string {
+	return schemaStringUnescaper.Replace(s)
+}
+
+// String renders the definition in the RFC 4512 format understood by the
+// server, e.g. as a value of olcAttributeTypes or olcObjectClasses.
+func (d *SchemaDefinition) String() string {
+	parts := []string{"(", d.OID}
+	if len(d.Names) > 0 {
+		quoted := make([]string, 0, len(d.Names))
+		for _, n := range d.Names {
+			quoted = append(quoted, quoteSchemaString(n))
+		}
+		parts = append(parts, "NAME", renderSchemaList(quoted, " "))
+	}
+	if d.Description != "" {
+		parts = append(parts, "DESC", quoteSchemaString(d.Description))
+	}
+	if d.Obsolete {
+		parts = append(parts, "OBSOLETE")
+	}
+	if len(d.Sup) > 0 {
+		parts = append(parts, "SUP", renderSchemaList(d.Sup, " $ "))
+	}
+	for _, field := range []struct{ keyword, value string }{
+		{"EQUALITY", d.Equality},
+		{"ORDERING", d.Ordering},
+		{"SUBSTR", d.Substr},
+		{"SYNTAX", d.Syntax},
+	} {
+		if field.value != "" {
+			parts = append(parts, field.keyword, field.value)
+		}
+	}
+	if d.SingleValue {
+		parts = append(parts, "SINGLE-VALUE")
+	}
+	if d.Collective {
+		parts = append(parts, "COLLECTIVE")
+	}
+	if d.NoUserModification {
+		parts = append(parts, "NO-USER-MODIFICATION")
+	}
+	if d.Usage != "" {
+		parts = append(parts, "USAGE", d.Usage)
+	}
+	if d.Kind != "" {
+		parts = append(parts, d.Kind)
+	}
+	if len(d.Must) > 0 {
+		parts = append(parts, "MUST", renderSchemaList(d.Must, " $ "))
+	}
+	if len(d.May) > 0 {
+		parts = append(parts, "MAY", renderSchemaList(d.May, " $ "))
+	}
+	extensions := make([]string, 0, len(d.Extensions))
+	for k := range d.Extensions {
+		extensions = append(extensions, k)
+	}
+	sort.Strings(extensions)
+	for _, k := range extensions {
+		quoted := make([]string, 0, len(d.Extensions[k]))
+		for _, v := range d.Extensions[k] {
+			quoted = append(quoted, quoteSchemaString(v))
+		}
+		parts = append(parts, k, renderSchemaList(quoted, " "))
+	}
+	parts = append(parts, ")")
+	return strings.Join(parts, " ")
+}
+
+func renderSchemaList(values []string, separator string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return "( " + strings.Join(values, separator) + " )"
+}
+
+var schemaStringEscaper = strings.NewReplacer(`\`, `\5C`, `'`, `\27`)
+
+func quoteSchemaString(s string) string {
+	return "'" + schemaStringEscaper.Replace(s) + "'"
+}
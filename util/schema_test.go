@@ -0,0 +1,137 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAttributeType(t *testing.T) {
+	d, err := ParseSchemaDefinition("( 2.5.4.3 NAME ( 'cn' 'commonName' ) DESC 'RFC4519: common name(s) for which the entity is known by' SUP name )")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.OID != "2.5.4.3" {
+		t.Errorf("Invalid OID, got %q", d.OID)
+	}
+	if !reflect.DeepEqual(d.Names, []string{"cn", "commonName"}) {
+		t.Errorf("Invalid names, got %v", d.Names)
+	}
+	if d.Description != "RFC4519: common name(s) for which the entity is known by" {
+		t.Errorf("Invalid description, got %q", d.Description)
+	}
+	if !reflect.DeepEqual(d.Sup, []string{"name"}) {
+		t.Errorf("Invalid sup, got %v", d.Sup)
+	}
+	if !d.HasName("COMMONNAME") || d.Name() != "cn" {
+		t.Errorf("Invalid name lookup for %v", d.Names)
+	}
+}
+
+func TestParseSingleValuedAttributeType(t *testing.T) {
+	d, err := ParseSchemaDefinition("( 1.3.6.1.1.16.4 NAME 'entryUUID' DESC 'UUID of the entry' EQUALITY UUIDMatch ORDERING UUIDOrderingMatch SYNTAX 1.3.6.1.1.16.1 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.Equality != "UUIDMatch" || d.Ordering != "UUIDOrderingMatch" || d.Syntax != "1.3.6.1.1.16.1" {
+		t.Errorf("Invalid matching rules or syntax, got %+v", d)
+	}
+	if !d.SingleValue || !d.NoUserModification || d.Usage != "directoryOperation" {
+		t.Errorf("Invalid flags, got %+v", d)
+	}
+}
+
+func TestParseObjectClass(t *testing.T) {
+	d, err := ParseSchemaDefinition("( 2.5.6.6 NAME 'person' DESC 'RFC2256: a person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY ( userPassword $ telephoneNumber $ seeAlso $ description ) X-ORIGIN 'RFC 4519' )")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.Kind != "STRUCTURAL" {
+		t.Errorf("Invalid kind, got %q", d.Kind)
+	}
+	if !reflect.DeepEqual(d.Must, []string{"sn", "cn"}) {
+		t.Errorf("Invalid must, got %v", d.Must)
+	}
+	if !reflect.DeepEqual(d.May, []string{"userPassword", "telephoneNumber", "seeAlso", "description"}) {
+		t.Errorf("Invalid may, got %v", d.May)
+	}
+	if !reflect.DeepEqual(d.Extensions["X-ORIGIN"], []string{"RFC 4519"}) {
+		t.Errorf("Invalid extensions, got %v", d.Extensions)
+	}
+}
+
+func TestParseEscapedDescription(t *testing.T) {
+	d, err := ParseSchemaDefinition(`( 1.2.3 DESC 'it\27s a \5Cpath' )`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.Description != `it's a \path` {
+		t.Errorf("Invalid description, got %q", d.Description)
+	}
+}
+
+func TestParseSyntaxInDescription(t *testing.T) {
+	d, err := ParseSchemaDefinition(`( 1.2.3 NAME ( 'a' 'b' ) DESC ')' SUP ( top $ person ) X-NOTE '( $ )' )`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.Description != ")" || !reflect.DeepEqual(d.Sup, []string{"top", "person"}) || !reflect.DeepEqual(d.Extensions["X-NOTE"], []string{"( $ )"}) {
+		t.Errorf("Unexpected definition %+v", d)
+	}
+	if d, err = ParseSchemaDefinition(`( 1.2.3 DESC '(' )`); err != nil || d.Description != "(" {
+		t.Errorf("Unexpected result %+v, %v", d, err)
+	}
+}
+
+func TestParseInvalidDefinitions(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"2.5.4.3 NAME 'cn'",
+		"( 2.5.4.3 NAME 'cn )",
+		"( 2.5.4.3 NAME ( 'cn' )",
+		"( 2.5.4.3 BOGUS 'cn' )",
+		"( 2.5.4.3 DESC )",
+	} {
+		if _, err := ParseSchemaDefinition(value); err == nil {
+			t.Errorf("Expected an error parsing %q", value)
+		}
+	}
+}
+
+func TestSchemaLookup(t *testing.T) {
+	s := &Schema{}
+	for _, value := range []string{
+		"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+		"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) )",
+	} {
+		d, err := ParseSchemaDefinition(value)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		s.ObjectClasses = append(s.ObjectClasses, d)
+	}
+	if oc := s.ObjectClass("Person"); oc == nil || oc.OID != "2.5.6.6" {
+		t.Errorf("Invalid lookup by name, got %+v", oc)
+	}
+	if oc := s.ObjectClass("2.5.6.0"); oc == nil || oc.Name() != "top" {
+		t.Errorf("Invalid lookup by OID, got %+v", oc)
+	}
+	if oc := s.ObjectClass("inetOrgPerson"); oc != nil {
+		t.Errorf("Unexpected lookup result %+v", oc)
+	}
+}
+
+func TestRenderRoundTrip(t *testing.T) {
+	for _, value := range []string{
+		"( 2.5.4.3 NAME ( 'cn' 'commonName' ) DESC 'it\\27s a name' SUP name )",
+		"( 1.3.6.1.1.16.4 NAME 'entryUUID' EQUALITY UUIDMatch ORDERING UUIDOrderingMatch SYNTAX 1.3.6.1.1.16.1 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )",
+		"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY ( userPassword $ description ) X-ORIGIN 'RFC 4519' )",
+	} {
+		d, err := ParseSchemaDefinition(value)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if d.String() != value {
+			t.Errorf("Invalid rendering, expected %q got %q", value, d.String())
+		}
+	}
+}